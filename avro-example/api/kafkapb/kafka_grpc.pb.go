@@ -0,0 +1,212 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: kafka.proto
+
+package kafkapb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Kafka_Produce_FullMethodName       = "/kafkaavro.Kafka/Produce"
+	Kafka_ProduceStream_FullMethodName = "/kafkaavro.Kafka/ProduceStream"
+	Kafka_Subscribe_FullMethodName     = "/kafkaavro.Kafka/Subscribe"
+)
+
+// KafkaClient is the client API for Kafka service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Kafka produces records to kafka topics and streams consumed records to clients.
+// Records are encoded either as json object with avro field names
+// or as avro binary encoding without schema registry framing.
+type KafkaClient interface {
+	// Produce sends record and waits for its delivery
+	Produce(ctx context.Context, in *ProduceRequest, opts ...grpc.CallOption) (*ProduceResponse, error)
+	// ProduceStream sends records of the stream and waits for delivery of all of them.
+	// The first invalid or failed record ends the call, records sent before it are still delivered.
+	ProduceStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ProduceRequest, ProduceStreamResponse], error)
+	// Subscribe streams records consumed by a consumer group until client cancels the call
+	// or server stops. Offsets are committed once records are sent to the client.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Record], error)
+}
+
+type kafkaClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewKafkaClient(cc grpc.ClientConnInterface) KafkaClient {
+	return &kafkaClient{cc}
+}
+
+func (c *kafkaClient) Produce(ctx context.Context, in *ProduceRequest, opts ...grpc.CallOption) (*ProduceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProduceResponse)
+	err := c.cc.Invoke(ctx, Kafka_Produce_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kafkaClient) ProduceStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ProduceRequest, ProduceStreamResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Kafka_ServiceDesc.Streams[0], Kafka_ProduceStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ProduceRequest, ProduceStreamResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Kafka_ProduceStreamClient = grpc.ClientStreamingClient[ProduceRequest, ProduceStreamResponse]
+
+func (c *kafkaClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Record], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Kafka_ServiceDesc.Streams[1], Kafka_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, Record]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Kafka_SubscribeClient = grpc.ServerStreamingClient[Record]
+
+// KafkaServer is the server API for Kafka service.
+// All implementations must embed UnimplementedKafkaServer
+// for forward compatibility.
+//
+// Kafka produces records to kafka topics and streams consumed records to clients.
+// Records are encoded either as json object with avro field names
+// or as avro binary encoding without schema registry framing.
+type KafkaServer interface {
+	// Produce sends record and waits for its delivery
+	Produce(context.Context, *ProduceRequest) (*ProduceResponse, error)
+	// ProduceStream sends records of the stream and waits for delivery of all of them.
+	// The first invalid or failed record ends the call, records sent before it are still delivered.
+	ProduceStream(grpc.ClientStreamingServer[ProduceRequest, ProduceStreamResponse]) error
+	// Subscribe streams records consumed by a consumer group until client cancels the call
+	// or server stops. Offsets are committed once records are sent to the client.
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Record]) error
+	mustEmbedUnimplementedKafkaServer()
+}
+
+// UnimplementedKafkaServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedKafkaServer struct{}
+
+func (UnimplementedKafkaServer) Produce(context.Context, *ProduceRequest) (*ProduceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Produce not implemented")
+}
+func (UnimplementedKafkaServer) ProduceStream(grpc.ClientStreamingServer[ProduceRequest, ProduceStreamResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ProduceStream not implemented")
+}
+func (UnimplementedKafkaServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Record]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedKafkaServer) mustEmbedUnimplementedKafkaServer() {}
+func (UnimplementedKafkaServer) testEmbeddedByValue()               {}
+
+// UnsafeKafkaServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to KafkaServer will
+// result in compilation errors.
+type UnsafeKafkaServer interface {
+	mustEmbedUnimplementedKafkaServer()
+}
+
+func RegisterKafkaServer(s grpc.ServiceRegistrar, srv KafkaServer) {
+	// If the following call pancis, it indicates UnimplementedKafkaServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Kafka_ServiceDesc, srv)
+}
+
+func _Kafka_Produce_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProduceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KafkaServer).Produce(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Kafka_Produce_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KafkaServer).Produce(ctx, req.(*ProduceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kafka_ProduceStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(KafkaServer).ProduceStream(&grpc.GenericServerStream[ProduceRequest, ProduceStreamResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Kafka_ProduceStreamServer = grpc.ClientStreamingServer[ProduceRequest, ProduceStreamResponse]
+
+func _Kafka_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KafkaServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, Record]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Kafka_SubscribeServer = grpc.ServerStreamingServer[Record]
+
+// Kafka_ServiceDesc is the grpc.ServiceDesc for Kafka service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Kafka_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kafkaavro.Kafka",
+	HandlerType: (*KafkaServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Produce",
+			Handler:    _Kafka_Produce_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ProduceStream",
+			Handler:       _Kafka_ProduceStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Kafka_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "kafka.proto",
+}
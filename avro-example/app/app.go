@@ -3,19 +3,33 @@ package app
 import (
 	"context"
 	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"time"
 
 	"github.com/AlexBlackNn/kafka-avro/avro-example/app/consumer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/app/gateway"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/app/grpcserver"
 	"github.com/AlexBlackNn/kafka-avro/avro-example/app/producer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/app/transformer"
 	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
 	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/logger"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/output"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/tracing"
 )
 
 var ErrWrongType = errors.New("wrong type")
 
+// ShutdownTimeout limits time of releasing shared resources (e.g. flushing spans)
+var ShutdownTimeout = 5 * time.Second
+
 type StartGetConfigStopper interface {
-	Start(ctx context.Context)
+	// Start runs application until ctx is done, it returns error
+	// when application can't go on, Stop has to be called anyway
+	Start(ctx context.Context) error
 	GetConfig() string
-	Stop()
+	Stop() error
 }
 
 func Fabric() (StartGetConfigStopper, error) {
@@ -23,12 +37,65 @@ func Fabric() (StartGetConfigStopper, error) {
 	if err != nil {
 		return nil, err
 	}
-	log := logger.New(cfg.Env)
-	if cfg.Kafka.Type == "producer" {
-		return producer.New(cfg, log)
+	// stdout is left to consumer output, logs and spans go to stderr
+	var logOutput io.Writer = os.Stdout
+	if cfg.Kafka.Type == "consumer" && cfg.Output.Format != output.FormatLog {
+		logOutput = os.Stderr
+	}
+	log := logger.New(cfg.Env, logOutput)
+
+	shutdownTracing, err := tracing.New(context.Background(), cfg.Tracing, logOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	var application StartGetConfigStopper
+	switch cfg.Kafka.Type {
+	case "producer":
+		application, err = producer.New(cfg, log)
+	case "consumer":
+		application, err = consumer.New(cfg, log)
+	case "transformer":
+		application, err = transformer.New(cfg, log)
+	case "gateway":
+		application, err = gateway.New(cfg, log)
+	case "grpc":
+		application, err = grpcserver.New(cfg, log)
+	default:
+		err = ErrWrongType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	shutdown := []func(ctx context.Context) error{shutdownTracing}
+	if cfg.Monitoring.Enabled {
+		shutdown = append(shutdown, startMonitoring(cfg.Monitoring, log, application))
 	}
-	if cfg.Kafka.Type == "consumer" {
-		return consumer.New(cfg, log)
+	return &withShutdown{
+		StartGetConfigStopper: application,
+		shutdown:              shutdown,
+		log:                   log,
+	}, nil
+}
+
+// withShutdown stops application and then releases resources shared by it
+type withShutdown struct {
+	StartGetConfigStopper
+	shutdown []func(ctx context.Context) error
+	log      *slog.Logger
+}
+
+func (a *withShutdown) Stop() error {
+	errs := []error{a.StartGetConfigStopper.Stop()}
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	for _, shutdown := range a.shutdown {
+		err := shutdown(ctx)
+		if err != nil {
+			a.log.Error("shutdown failed", "err", err.Error())
+			errs = append(errs, err)
+		}
 	}
-	return nil, ErrWrongType
+	return errors.Join(errs...)
 }
@@ -2,61 +2,190 @@ package consumer
 
 import (
 	"context"
-	"log"
+	"errors"
 	"log/slog"
-	"time"
+	"os"
+	"sync"
 
 	consumer "github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/consumer"
 	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/health"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/output"
 )
 
 type consumeCloser interface {
-	Consume() error
+	Consume(ctx context.Context) error
 	Close() error
+	health.Checker
 }
 
 type App struct {
 	ServerConsumer consumeCloser
-	log            *slog.Logger
-	Cfg            *config.Config
+	// consumers of retry topics, empty when retries are disabled
+	RetryConsumers []consumeCloser
+	// sink writes received users to stdout, they are logged when it is nil
+	sink output.Sink[dto.User]
+	log  *slog.Logger
+	Cfg  *config.Config
 }
 
 func New(cfg *config.Config, log *slog.Logger) (*App, error) {
-
-	cons, err := consumer.New(cfg, log)
+	a := &App{
+		log: log,
+		Cfg: cfg,
+	}
+	if cfg.Output.Format != output.FormatLog {
+		sink, err := output.New[dto.User](cfg.Output.Format, os.Stdout, cfg.Output.Columns)
+		if err != nil {
+			return nil, err
+		}
+		a.sink = sink
+	}
+	if cfg.Kafka.Batch.Enabled {
+		cons, err := consumer.NewBatch[dto.User](cfg, log, consumer.BatchHandlerFunc[dto.User](a.handleBatch))
+		if err != nil {
+			return nil, err
+		}
+		a.ServerConsumer = cons
+		return a, nil
+	}
+	cons, err := consumer.New[dto.User](cfg, log, consumer.HandlerFunc[dto.User](a.handle))
 	if err != nil {
 		return nil, err
 	}
+	a.ServerConsumer = cons
+	for _, stage := range cons.RetryStages() {
+		a.RetryConsumers = append(a.RetryConsumers, stage)
+	}
+	return a, nil
+}
 
-	return &App{
-		ServerConsumer: cons,
-		log:            log,
-		Cfg:            cfg,
-	}, nil
+// Start consumes messages until ctx is done. When any consumer fails with
+// non-recoverable error, the others are stopped too and the error is returned.
+func (a *App) Start(ctx context.Context) error {
+	a.log.Info("consumer starts")
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// retry consumers wait for retry time, so each of them
+	// is polled by its own goroutine
+	errs := make([]error, len(a.RetryConsumers)+1)
+	var wg sync.WaitGroup
+	for i, retryConsumer := range a.RetryConsumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i+1] = a.consume(ctx, retryConsumer)
+			if errs[i+1] != nil {
+				cancel()
+			}
+		}()
+	}
+	errs[0] = a.consume(ctx, a.ServerConsumer)
+	cancel()
+	wg.Wait()
+	return errors.Join(errs...)
 }
 
-func (a *App) Start(ctx context.Context) {
-	a.log.Info("producer starts")
+// consume polls cons until ctx is done or cons fails with non-recoverable error
+func (a *App) consume(ctx context.Context, cons consumeCloser) error {
 	for {
 		select {
 		case <-ctx.Done():
-			return
+			return nil
 		default:
-			err := a.ServerConsumer.Consume()
+			err := cons.Consume(ctx)
 			if err != nil {
-				log.Fatal(err.Error())
+				if !isRecoverable(err) {
+					return err
+				}
+				// the message will be consumed again or
+				// its offset will be committed with the next batch
+				a.log.Error("message processing failed", "err", err.Error())
 			}
-			time.Sleep(time.Second)
 		}
 	}
 }
 
-func (a *App) Stop() {
+// isRecoverable reports whether consuming may go on after err
+func isRecoverable(err error) bool {
+	// dead letter topic may be temporary unavailable,
+	// while the source message is going to be consumed again
+	if errors.Is(err, consumer.ErrDeadLetter) || errors.Is(err, consumer.ErrRetry) {
+		return true
+	}
+	return errors.Is(err, consumer.ErrHandle) || errors.Is(err, consumer.ErrCommit)
+}
+
+// handle writes received user to output
+func (a *App) handle(_ context.Context, msg consumer.Record[dto.User]) error {
+	if a.sink != nil {
+		return a.sink.Write(msg)
+	}
+	a.log.Info(
+		"Message received",
+		"topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "message", msg.Value,
+	)
+	return nil
+}
+
+func (a *App) handleBatch(ctx context.Context, msgs []consumer.Record[dto.User]) error {
+	a.log.Info("Batch received", "size", len(msgs))
+	for _, msg := range msgs {
+		err := a.handle(ctx, msg)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop commits processed messages and closes kafka clients.
+// It must be called after Start returned, so no message is in flight.
+func (a *App) Stop() error {
 	a.log.Info("close kafka client")
+	var errs []error
+	// retry consumers share producer of the source consumer, so they go first
+	for _, retryConsumer := range a.RetryConsumers {
+		err := retryConsumer.Close()
+		if err != nil {
+			a.log.Error(err.Error())
+			errs = append(errs, err)
+		}
+	}
 	err := a.ServerConsumer.Close()
 	if err != nil {
 		a.log.Error(err.Error())
+		errs = append(errs, err)
+	}
+	if a.sink != nil {
+		err = a.sink.Close()
+		if err != nil {
+			a.log.Error(err.Error())
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Live reports whether poll loops of all consumers are alive
+func (a *App) Live() error {
+	for _, retryConsumer := range a.RetryConsumers {
+		err := retryConsumer.Live()
+		if err != nil {
+			return err
+		}
+	}
+	return a.ServerConsumer.Live()
+}
+
+// Ready reports whether the source topic consumer is able to consume messages
+func (a *App) Ready(ctx context.Context) error {
+	err := a.ServerConsumer.Ready(ctx)
+	if err != nil {
+		return err
 	}
+	return a.Live()
 }
 
 func (a *App) GetConfig() string {
@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/producer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/health"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+type syncSendCloser interface {
+	SendSync(ctx context.Context, msg dto.User, topic string, key []byte) (kafka.TopicPartition, error)
+	Key(msg dto.User) ([]byte, error)
+	Close() error
+	health.Checker
+}
+
+// App is http gateway producing request bodies to kafka
+type App struct {
+	ServerProducer syncSendCloser
+	server         *http.Server
+	// topics requests may be sent to
+	topics map[string]bool
+	log    *slog.Logger
+	Cfg    *config.Config
+}
+
+func New(cfg *config.Config, log *slog.Logger) (*App, error) {
+	prod, err := producer.New[dto.User](cfg, log)
+	if err != nil {
+		return nil, err
+	}
+
+	topics := cfg.Gateway.Topics
+	if len(topics) == 0 {
+		topics = []string{cfg.Kafka.Topic}
+	}
+	a := &App{
+		ServerProducer: prod,
+		topics:         make(map[string]bool, len(topics)),
+		log:            log.With("address", cfg.Gateway.Address),
+		Cfg:            cfg,
+	}
+	for _, topic := range topics {
+		a.topics[topic] = true
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /topics/{topic}", a.produce)
+	a.server = &http.Server{
+		Addr:              cfg.Gateway.Address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return a, nil
+}
+
+// Start serves http requests until ctx is done, then it waits
+// for in-flight requests no longer than gateway.shutdownTimeout
+func (a *App) Start(ctx context.Context) error {
+	a.log.Info("gateway starts")
+	a.server.BaseContext = func(net.Listener) context.Context {
+		// requests aren't canceled on shutdown, they are drained instead
+		return context.WithoutCancel(ctx)
+	}
+	failed := make(chan error, 1)
+	go func() {
+		failed <- a.server.ListenAndServe()
+	}()
+	select {
+	case err := <-failed:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("gateway failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	a.log.Info("gateway stops accepting requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.Cfg.Gateway.ShutdownTimeout)
+	defer cancel()
+	err := a.server.Shutdown(shutdownCtx)
+	if err != nil {
+		return fmt.Errorf("gateway shutdown failed: %w", err)
+	}
+	return nil
+}
+
+// Stop waits for delivery of sent messages and closes kafka client,
+// it must be called after Start returned
+func (a *App) Stop() error {
+	a.log.Info("close kafka client")
+	err := a.ServerProducer.Close()
+	if err != nil {
+		a.log.Error(err.Error())
+	}
+	return err
+}
+
+// Live reports whether the application is alive
+func (a *App) Live() error {
+	return a.ServerProducer.Live()
+}
+
+// Ready reports whether the application is able to send messages
+func (a *App) Ready(ctx context.Context) error {
+	return a.ServerProducer.Ready(ctx)
+}
+
+func (a *App) GetConfig() string {
+	return a.Cfg.String()
+}
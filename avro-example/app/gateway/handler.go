@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/producer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/metrics"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/validation"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// deliveryResponse is a response to successfully delivered message
+type deliveryResponse struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+}
+
+// errorResponse is a response to failed request, Fields lists invalid fields of record
+type errorResponse struct {
+	Error  string                  `json:"error"`
+	Fields []validation.FieldError `json:"fields,omitempty"`
+}
+
+// produce handles POST /topics/{topic}: JSON body is decoded by avro schema compiled
+// into the record type, not by the schema version registered for the topic subject,
+// validated and produced to the topic. It responds with partition and offset of the message
+// once it is delivered.
+func (a *App) produce(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	topic := r.PathValue("topic")
+	label := topic
+	if !a.topics[topic] {
+		// path isn't used as metric label, so unknown topics don't blow up cardinality
+		label = "unknown"
+	}
+	status := a.handle(w, r, topic)
+	metrics.GatewayRequests.WithLabelValues(label, strconv.Itoa(status)).Inc()
+	metrics.GatewayRequestDuration.WithLabelValues(label).Observe(time.Since(start).Seconds())
+}
+
+// handle produces request body and writes response, it returns response status code
+func (a *App) handle(w http.ResponseWriter, r *http.Request, topic string) int {
+	if !a.topics[topic] {
+		return a.writeError(w, http.StatusNotFound, fmt.Errorf("topic %s isn't served by gateway", topic))
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, a.Cfg.Gateway.MaxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return a.writeError(w, http.StatusRequestEntityTooLarge, err)
+		}
+		return a.writeError(w, http.StatusBadRequest, err)
+	}
+	var msg dto.User
+	err = json.Unmarshal(body, &msg)
+	if err != nil {
+		return a.writeError(w, http.StatusBadRequest, fmt.Errorf("decoding record: %w", err))
+	}
+
+	key := []byte(r.Header.Get(a.Cfg.Gateway.KeyHeader))
+	if len(key) == 0 {
+		key, err = a.ServerProducer.Key(msg)
+		if err != nil {
+			return a.writeError(w, statusOf(err), err)
+		}
+	}
+	// trace context of the caller is continued by producer span
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, cancel := context.WithTimeout(ctx, a.Cfg.Gateway.DeliveryTimeout)
+	defer cancel()
+	tp, err := a.ServerProducer.SendSync(ctx, msg, topic, key)
+	if err != nil {
+		return a.writeError(w, statusOf(err), err)
+	}
+	return a.writeJSON(w, http.StatusOK, deliveryResponse{
+		Topic:     *tp.Topic,
+		Partition: tp.Partition,
+		Offset:    int64(tp.Offset),
+	})
+}
+
+// statusOf maps producer error to http status code
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, producer.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, producer.ErrMessageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, producer.ErrUnknownTopic):
+		return http.StatusNotFound
+	case errors.Is(err, producer.ErrSerialization):
+		// schema registry is unavailable or rejected the schema
+		return http.StatusBadGateway
+	case errors.Is(err, producer.ErrQueueFull), errors.Is(err, producer.ErrFatal):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError writes error response, invalid fields are listed for validation errors
+func (a *App) writeError(w http.ResponseWriter, status int, err error) int {
+	if status >= http.StatusInternalServerError {
+		a.log.Error("producing request failed", "status", status, "err", err.Error())
+	} else {
+		a.log.Warn("request rejected", "status", status, "err", err.Error())
+	}
+	response := errorResponse{Error: err.Error()}
+	var invalid *validation.Error
+	if errors.As(err, &invalid) {
+		response.Fields = invalid.Fields
+	}
+	if status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "1")
+	}
+	return a.writeJSON(w, status, response)
+}
+
+func (a *App) writeJSON(w http.ResponseWriter, status int, response any) int {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		a.log.Error("writing response failed", "err", err.Error())
+	}
+	return status
+}
@@ -0,0 +1,199 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/producer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/validation"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// fakeProducer records sent messages and fails with err if it's set
+type fakeProducer struct {
+	err error
+	msg dto.User
+	key []byte
+}
+
+func (p *fakeProducer) SendSync(_ context.Context, msg dto.User, topic string, key []byte) (kafka.TopicPartition, error) {
+	if p.err != nil {
+		return kafka.TopicPartition{}, p.err
+	}
+	p.msg, p.key = msg, key
+	return kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 42}, nil
+}
+
+func (p *fakeProducer) Key(msg dto.User) ([]byte, error) {
+	return []byte("key-" + msg.Name), nil
+}
+
+func (p *fakeProducer) Close() error                { return nil }
+func (p *fakeProducer) Live() error                 { return nil }
+func (p *fakeProducer) Ready(context.Context) error { return nil }
+
+func newTestApp(prod *fakeProducer) *App {
+	return &App{
+		ServerProducer: prod,
+		topics:         map[string]bool{"users": true},
+		log:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Cfg: &config.Config{Gateway: config.GatewayConfig{
+			KeyHeader:       "X-Message-Key",
+			MaxBodySize:     128,
+			DeliveryTimeout: time.Second,
+		}},
+	}
+}
+
+const alice = `{"name":"Alice","favorite_number":7,"favorite_color":"red"}`
+
+func TestProduce(t *testing.T) {
+	invalid := &validation.Error{Fields: []validation.FieldError{
+		{Field: "name", Rule: validation.RuleNotEmpty, Message: "must not be empty"},
+	}}
+	tests := []struct {
+		name       string
+		topic      string
+		body       string
+		sendErr    error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "delivered",
+			topic:      "users",
+			body:       alice,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"topic":"users","partition":1,"offset":42}`,
+		},
+		{
+			name:       "malformed json",
+			topic:      "users",
+			body:       `{"name":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing schema field",
+			topic:      "users",
+			body:       `{"name":"Alice"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown topic",
+			topic:      "orders",
+			body:       alice,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "body too large",
+			topic:      "users",
+			body:       `{"name":"` + strings.Repeat("a", 128) + `","favorite_number":7,"favorite_color":"red"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "invalid record",
+			topic:      "users",
+			body:       alice,
+			sendErr:    fmt.Errorf("%w: %w", producer.ErrValidation, invalid),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody:   `{"error":"message validation failed: name: must not be empty","fields":[{"field":"name","rule":"notEmpty","message":"must not be empty"}]}`,
+		},
+		{
+			name:       "queue full",
+			topic:      "users",
+			body:       alice,
+			sendErr:    fmt.Errorf("%w: %w", producer.ErrQueueFull, kafka.NewError(kafka.ErrQueueFull, "queue full", false)),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "producer failed",
+			topic:      "users",
+			body:       alice,
+			sendErr:    fmt.Errorf("%w: fenced", producer.ErrFatal),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "not delivered in time",
+			topic:      "users",
+			body:       alice,
+			sendErr:    context.DeadlineExceeded,
+			wantStatus: http.StatusGatewayTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prod := &fakeProducer{err: tt.sendErr}
+			a := newTestApp(prod)
+			r := httptest.NewRequest(http.MethodPost, "/topics/"+tt.topic, strings.NewReader(tt.body))
+			r.SetPathValue("topic", tt.topic)
+			w := httptest.NewRecorder()
+			a.produce(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("got content type %q", got)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(w.Body.String()) != tt.wantBody {
+				t.Errorf("got body %s, want %s", w.Body, tt.wantBody)
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable && w.Header().Get("Retry-After") == "" {
+				t.Errorf("Retry-After isn't set for %d", w.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				var response errorResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				if err != nil || response.Error == "" {
+					t.Errorf("got error response %s", w.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestProduceKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"from header", "alex", "alex"},
+		{"by key strategy", "", "key-Alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prod := &fakeProducer{}
+			a := newTestApp(prod)
+			r := httptest.NewRequest(http.MethodPost, "/topics/users", strings.NewReader(alice))
+			r.SetPathValue("topic", "users")
+			if tt.header != "" {
+				r.Header.Set("X-Message-Key", tt.header)
+			}
+			w := httptest.NewRecorder()
+			a.produce(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("got status %d: %s", w.Code, w.Body)
+			}
+			if string(prod.key) != tt.want {
+				t.Errorf("got key %q, want %q", prod.key, tt.want)
+			}
+			want := dto.User{Name: "Alice", Favorite_number: 7, Favorite_color: "red"}
+			if !reflect.DeepEqual(prod.msg, want) {
+				t.Errorf("got message %+v, want %+v", prod.msg, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/api/kafkapb"
+	consumer "github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/consumer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/producer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/health"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// offsetResets maps requested offset to auto.offset.reset of subscription consumer
+var offsetResets = map[kafkapb.Offset]string{
+	kafkapb.Offset_OFFSET_UNSPECIFIED: "latest",
+	kafkapb.Offset_OFFSET_LATEST:      "latest",
+	kafkapb.Offset_OFFSET_EARLIEST:    "earliest",
+}
+
+type sendCloser interface {
+	SendAsync(ctx context.Context, msg dto.User, topic string, key []byte) (<-chan producer.Delivery, error)
+	SendSync(ctx context.Context, msg dto.User, topic string, key []byte) (kafka.TopicPartition, error)
+	Key(msg dto.User) ([]byte, error)
+	Close() error
+	health.Checker
+}
+
+// App is grpc service producing records to kafka and streaming consumed records to clients,
+// the service is described by api/kafkapb/kafka.proto
+type App struct {
+	kafkapb.UnimplementedKafkaServer
+	ServerProducer sendCloser
+	server         *grpc.Server
+	// topics records may be produced to or subscribed to
+	topics map[string]bool
+	// done is closed on shutdown to end subscriptions
+	done chan struct{}
+	log  *slog.Logger
+	Cfg  *config.Config
+}
+
+func New(cfg *config.Config, log *slog.Logger) (*App, error) {
+	prod, err := producer.New[dto.User](cfg, log)
+	if err != nil {
+		return nil, err
+	}
+
+	topics := cfg.GRPC.Topics
+	if len(topics) == 0 {
+		topics = []string{cfg.Kafka.Topic}
+	}
+	a := &App{
+		ServerProducer: prod,
+		topics:         make(map[string]bool, len(topics)),
+		done:           make(chan struct{}),
+		log:            log.With("address", cfg.GRPC.Address),
+		Cfg:            cfg,
+	}
+	for _, topic := range topics {
+		a.topics[topic] = true
+	}
+	a.server = grpc.NewServer(
+		// trace context of the caller is continued by producer spans
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+	)
+	kafkapb.RegisterKafkaServer(a.server, a)
+	return a, nil
+}
+
+// Start serves grpc calls until ctx is done, then it ends subscriptions
+// and waits for other calls no longer than grpc.shutdownTimeout
+func (a *App) Start(ctx context.Context) error {
+	a.log.Info("grpc server starts")
+	listener, err := net.Listen("tcp", a.Cfg.GRPC.Address)
+	if err != nil {
+		return fmt.Errorf("grpc server failed: %w", err)
+	}
+	return a.serve(ctx, listener)
+}
+
+// serve serves grpc calls on listener until ctx is done, see Start
+func (a *App) serve(ctx context.Context, listener net.Listener) error {
+	failed := make(chan error, 1)
+	go func() {
+		failed <- a.server.Serve(listener)
+	}()
+	select {
+	case err := <-failed:
+		if err != nil {
+			return fmt.Errorf("grpc server failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	a.log.Info("grpc server stops accepting calls")
+	close(a.done)
+	stopped := make(chan struct{})
+	go func() {
+		a.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(a.Cfg.GRPC.ShutdownTimeout):
+		a.log.Warn("grpc calls are canceled on shutdown timeout")
+		a.server.Stop()
+	}
+	return nil
+}
+
+// Produce sends record and waits for its delivery
+func (a *App) Produce(ctx context.Context, req *kafkapb.ProduceRequest) (*kafkapb.ProduceResponse, error) {
+	msg, key, err := a.decode(req)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, a.Cfg.GRPC.DeliveryTimeout)
+	defer cancel()
+	tp, err := a.ServerProducer.SendSync(ctx, msg, req.Topic, key)
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	return produceResponse(tp), nil
+}
+
+// ProduceStream sends records of the stream and waits for delivery of all of them.
+// The first invalid or failed record ends the call, records sent before it are still delivered.
+func (a *App) ProduceStream(stream grpc.ClientStreamingServer[kafkapb.ProduceRequest, kafkapb.ProduceStreamResponse]) error {
+	var results []<-chan producer.Delivery
+	for i := 0; ; i++ {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		msg, key, err := a.decode(req)
+		if err != nil {
+			return recordStatus(i, err)
+		}
+		result, err := a.ServerProducer.SendAsync(stream.Context(), msg, req.Topic, key)
+		if err != nil {
+			return recordStatus(i, statusOf(err))
+		}
+		results = append(results, result)
+	}
+
+	ctx, cancel := context.WithTimeout(stream.Context(), a.Cfg.GRPC.DeliveryTimeout)
+	defer cancel()
+	response := &kafkapb.ProduceStreamResponse{Deliveries: make([]*kafkapb.ProduceResponse, 0, len(results))}
+	for i, result := range results {
+		select {
+		case <-ctx.Done():
+			return recordStatus(i, statusOf(ctx.Err()))
+		case d := <-result:
+			if d.Err != nil {
+				return recordStatus(i, statusOf(d.Err))
+			}
+			response.Deliveries = append(response.Deliveries, produceResponse(d.TopicPartition))
+		}
+	}
+	return stream.SendAndClose(response)
+}
+
+// Subscribe streams records consumed by a new consumer of the requested group
+// until client cancels the call or server stops. Offset of a record is
+// committed after it's sent to the client, so records are delivered at least once.
+func (a *App) Subscribe(req *kafkapb.SubscribeRequest, stream grpc.ServerStreamingServer[kafkapb.Record]) error {
+	cfg, err := a.subscriptionConfig(req)
+	if err != nil {
+		return err
+	}
+	format := req.GetFormat()
+	if _, ok := kafkapb.Format_name[int32(format)]; !ok {
+		return status.Errorf(codes.InvalidArgument, "unknown format %d", format)
+	}
+
+	handler := func(_ context.Context, msg consumer.Record[dto.User]) error {
+		record, err := encode(msg, format)
+		if err != nil {
+			return err
+		}
+		return stream.Send(record)
+	}
+	cons, err := consumer.New[dto.User](cfg, a.log, consumer.HandlerFunc[dto.User](handler))
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	log := a.log.With("group", cfg.Kafka.GroupID, "topics", cfg.Kafka.Topics)
+	log.Info("subscription starts")
+	defer func() {
+		err := cons.Close()
+		if err != nil {
+			log.Error("closing subscription failed", "err", err.Error())
+		}
+		log.Info("subscription ends")
+	}()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-a.done:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		default:
+		}
+		err := cons.Consume(ctx)
+		if err != nil && ctx.Err() == nil {
+			// the record is consumed again by the next call
+			log.Warn("consuming subscription failed", "err", err.Error())
+		}
+	}
+}
+
+// subscriptionConfig returns config of subscription consumer, it has neither
+// retry nor dead letter topics, as failed records are just sent to client again
+func (a *App) subscriptionConfig(req *kafkapb.SubscribeRequest) (*config.Config, error) {
+	if len(req.Topics) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no topics to subscribe to")
+	}
+	for _, topic := range req.Topics {
+		if !a.topics[topic] {
+			return nil, status.Errorf(codes.NotFound, "topic %s isn't served", topic)
+		}
+	}
+	offset, ok := offsetResets[req.Offset]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown offset %d", req.Offset)
+	}
+	groupID := req.GroupId
+	if groupID == "" {
+		groupID = "grpc-" + uuid.NewString()
+	}
+
+	cfg := *a.Cfg
+	cfg.Kafka.Topics = req.Topics
+	cfg.Kafka.TopicPattern = ""
+	cfg.Kafka.GroupID = groupID
+	cfg.Kafka.AutoOffsetReset = offset
+	cfg.Kafka.DLQ.Enabled = false
+	cfg.Kafka.Retry.Enabled = false
+	cfg.Kafka.Batch.Enabled = false
+	cfg.Kafka.Workers.Enabled = false
+	return &cfg, nil
+}
+
+// decode returns record and key of request, key is chosen by key strategy when request has none
+func (a *App) decode(req *kafkapb.ProduceRequest) (dto.User, []byte, error) {
+	var msg dto.User
+	if !a.topics[req.Topic] {
+		return msg, nil, status.Errorf(codes.NotFound, "topic %s isn't served", req.Topic)
+	}
+	var err error
+	switch value := req.Value.(type) {
+	case *kafkapb.ProduceRequest_Json:
+		err = json.Unmarshal(value.Json, &msg)
+	case *kafkapb.ProduceRequest_Avro:
+		msg, err = dto.DeserializeUser(bytes.NewReader(value.Avro))
+	default:
+		return msg, nil, status.Error(codes.InvalidArgument, "either json or avro value has to be set")
+	}
+	if err != nil {
+		return msg, nil, status.Errorf(codes.InvalidArgument, "decoding record: %s", err)
+	}
+	key := req.Key
+	if len(key) == 0 {
+		key, err = a.ServerProducer.Key(msg)
+		if err != nil {
+			return msg, nil, statusOf(err)
+		}
+	}
+	return msg, key, nil
+}
+
+// encode turns consumed record into grpc message with value in the given format
+func encode(msg consumer.Record[dto.User], format kafkapb.Format) (*kafkapb.Record, error) {
+	record := &kafkapb.Record{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    int64(msg.Offset),
+		Key:       msg.Key,
+		Timestamp: timestamppb.New(msg.Timestamp),
+	}
+	if format == kafkapb.Format_FORMAT_AVRO {
+		var buf bytes.Buffer
+		err := msg.Value.Serialize(&buf)
+		if err != nil {
+			return nil, err
+		}
+		record.Value = &kafkapb.Record_Avro{Avro: buf.Bytes()}
+		return record, nil
+	}
+	value, err := json.Marshal(msg.Value)
+	if err != nil {
+		return nil, err
+	}
+	record.Value = &kafkapb.Record_Json{Json: value}
+	return record, nil
+}
+
+func produceResponse(tp kafka.TopicPartition) *kafkapb.ProduceResponse {
+	return &kafkapb.ProduceResponse{
+		Topic:     *tp.Topic,
+		Partition: tp.Partition,
+		Offset:    int64(tp.Offset),
+	}
+}
+
+// statusOf maps producer error to grpc status
+func statusOf(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, producer.ErrValidation), errors.Is(err, producer.ErrMessageTooLarge):
+		code = codes.InvalidArgument
+	case errors.Is(err, producer.ErrUnknownTopic):
+		code = codes.NotFound
+	case errors.Is(err, producer.ErrQueueFull):
+		code = codes.ResourceExhausted
+	case errors.Is(err, producer.ErrNoKeyFunc):
+		code = codes.FailedPrecondition
+	case errors.Is(err, producer.ErrSerialization), errors.Is(err, producer.ErrFatal):
+		code = codes.Unavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	}
+	return status.Error(code, err.Error())
+}
+
+// recordStatus adds index of record in stream to error status
+func recordStatus(i int, err error) error {
+	s := status.Convert(err)
+	return status.Error(s.Code(), fmt.Sprintf("record %d: %s", i, s.Message()))
+}
+
+// Stop waits for delivery of sent messages and closes kafka client,
+// it must be called after Start returned
+func (a *App) Stop() error {
+	a.log.Info("close kafka client")
+	err := a.ServerProducer.Close()
+	if err != nil {
+		a.log.Error(err.Error())
+	}
+	return err
+}
+
+// Live reports whether the application is alive
+func (a *App) Live() error {
+	return a.ServerProducer.Live()
+}
+
+// Ready reports whether the application is able to send messages
+func (a *App) Ready(ctx context.Context) error {
+	return a.ServerProducer.Ready(ctx)
+}
+
+func (a *App) GetConfig() string {
+	return a.Cfg.String()
+}
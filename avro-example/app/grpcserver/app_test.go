@@ -0,0 +1,300 @@
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/api/kafkapb"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testTopic = "users"
+
+// testConfig is loaded with bootstrap servers of mock cluster,
+// producer and consumers share mock schema registry of the test
+const testConfig = `
+kafka:
+  kafkaUrl: %q
+  schemaRegistryURL: "mock://%s"
+  topic: %q
+  groupId: "grpc-test"
+  key:
+    strategy: "field"
+    field: "User.Name"
+validation:
+  rules:
+    favorite_color:
+      enum: ["red", "green", "blue"]
+grpc:
+  deliveryTimeout: "10s"
+  shutdownTimeout: "1s"
+`
+
+// newTestClient starts App on in-memory listener against kafka mock cluster
+// and returns client connected to it
+func newTestClient(t *testing.T) kafkapb.KafkaClient {
+	t.Helper()
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cluster.Close)
+	err = cluster.CreateTopic(testTopic, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := fmt.Sprintf(testConfig, cluster.BootstrapServers(), t.Name(), testTopic)
+	err = os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadByPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a, err := New(cfg, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- a.serve(ctx, listener)
+	}()
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		if err := <-served; err != nil {
+			t.Error(err)
+		}
+		if err := a.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+	return kafkapb.NewKafkaClient(conn)
+}
+
+func jsonValue(t *testing.T, user dto.User) *kafkapb.ProduceRequest_Json {
+	t.Helper()
+	value, err := json.Marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &kafkapb.ProduceRequest_Json{Json: value}
+}
+
+func avroValue(t *testing.T, user dto.User) *kafkapb.ProduceRequest_Avro {
+	t.Helper()
+	var buf bytes.Buffer
+	err := user.Serialize(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &kafkapb.ProduceRequest_Avro{Avro: buf.Bytes()}
+}
+
+func TestProduce(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	user := dto.User{Name: "Alice", Favorite_number: 7, Favorite_color: "red"}
+
+	requests := []*kafkapb.ProduceRequest{
+		{Topic: testTopic, Value: jsonValue(t, user)},
+		{Topic: testTopic, Value: avroValue(t, user)},
+	}
+	for i, req := range requests {
+		resp, err := client.Produce(ctx, req)
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if resp.Topic != testTopic || resp.Partition != 0 || resp.Offset != int64(i) {
+			t.Errorf("record %d: delivered to %s[%d]@%d", i, resp.Topic, resp.Partition, resp.Offset)
+		}
+	}
+
+	tests := []struct {
+		name string
+		req  *kafkapb.ProduceRequest
+		code codes.Code
+	}{
+		{
+			name: "no value",
+			req:  &kafkapb.ProduceRequest{Topic: testTopic},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "malformed json",
+			req: &kafkapb.ProduceRequest{
+				Topic: testTopic, Value: &kafkapb.ProduceRequest_Json{Json: []byte("{")},
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "invalid record",
+			req: &kafkapb.ProduceRequest{
+				Topic: testTopic, Value: jsonValue(t, dto.User{Name: "Bob", Favorite_color: "pink"}),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "topic isn't served",
+			req:  &kafkapb.ProduceRequest{Topic: "orders", Value: jsonValue(t, user)},
+			code: codes.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Produce(ctx, tt.req)
+			if code := status.Code(err); code != tt.code {
+				t.Errorf("got code %s, want %s: %v", code, tt.code, err)
+			}
+		})
+	}
+}
+
+func TestProduceStream(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	stream, err := client.ProduceStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"Alice", "Bob", "Carol"}
+	for _, name := range names {
+		user := dto.User{Name: name, Favorite_color: "green"}
+		err := stream.Send(&kafkapb.ProduceRequest{Topic: testTopic, Key: []byte(name), Value: avroValue(t, user)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Deliveries) != len(names) {
+		t.Fatalf("got %d deliveries, want %d", len(resp.Deliveries), len(names))
+	}
+	for i, d := range resp.Deliveries {
+		if d.Offset != int64(i) {
+			t.Errorf("record %d: delivered at offset %d", i, d.Offset)
+		}
+	}
+
+	// the first invalid record ends the call
+	stream, err = client.ProduceStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := &kafkapb.ProduceRequest{Topic: testTopic, Value: jsonValue(t, dto.User{Name: "Dave", Favorite_color: "blue"})}
+	invalid := &kafkapb.ProduceRequest{Topic: testTopic}
+	for _, req := range []*kafkapb.ProduceRequest{valid, invalid, valid} {
+		if err := stream.Send(req); err != nil {
+			// server has already ended the call
+			break
+		}
+	}
+	_, err = stream.CloseAndRecv()
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Fatalf("got code %s, want %s: %v", code, codes.InvalidArgument, err)
+	}
+	if msg := status.Convert(err).Message(); !strings.HasPrefix(msg, "record 1:") {
+		t.Errorf("error doesn't point to invalid record: %s", msg)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	users := []dto.User{
+		{Name: "Alice", Favorite_number: 1, Favorite_color: "red"},
+		{Name: "Bob", Favorite_number: 2, Favorite_color: "blue"},
+	}
+	for _, user := range users {
+		_, err := client.Produce(ctx, &kafkapb.ProduceRequest{Topic: testTopic, Value: jsonValue(t, user)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, format := range []kafkapb.Format{kafkapb.Format_FORMAT_JSON, kafkapb.Format_FORMAT_AVRO} {
+		t.Run(format.String(), func(t *testing.T) {
+			// every subscription is a new group, so it reads topic from the beginning
+			stream, err := client.Subscribe(ctx, &kafkapb.SubscribeRequest{
+				Topics: []string{testTopic},
+				Offset: kafkapb.Offset_OFFSET_EARLIEST,
+				Format: format,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range users {
+				record, err := stream.Recv()
+				if err != nil {
+					t.Fatalf("record %d: %v", i, err)
+				}
+				if record.Offset != int64(i) || string(record.Key) != want.Name {
+					t.Errorf("record %d: got offset %d key %q", i, record.Offset, record.Key)
+				}
+				var got dto.User
+				switch value := record.Value.(type) {
+				case *kafkapb.Record_Json:
+					err = json.Unmarshal(value.Json, &got)
+				case *kafkapb.Record_Avro:
+					got, err = dto.DeserializeUser(bytes.NewReader(value.Avro))
+				default:
+					t.Fatalf("record %d has no value", i)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("record %d: got %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+
+	stream, err := client.Subscribe(ctx, &kafkapb.SubscribeRequest{Topics: []string{"orders"}})
+	if err == nil {
+		// errors of server streaming calls are returned by Recv
+		_, err = stream.Recv()
+	}
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("got code %s, want %s: %v", code, codes.NotFound, err)
+	}
+}
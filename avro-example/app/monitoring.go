@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/health"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/metrics"
+)
+
+// CheckTimeout limits time of readiness check
+var CheckTimeout = 2 * time.Second
+
+// startMonitoring starts http server exposing /metrics, /healthz and /readyz,
+// returned function stops the server
+func startMonitoring(cfg config.MonitoringConfig, log *slog.Logger, application any) func(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	if checker, ok := application.(health.Checker); ok {
+		mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+			writeCheck(w, log, checker.Live())
+		})
+		mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), CheckTimeout)
+			defer cancel()
+			writeCheck(w, log, checker.Ready(ctx))
+		})
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.Address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		log.Info("monitoring server starts", "address", cfg.Address)
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("monitoring server failed", "err", err.Error())
+		}
+	}()
+	return srv.Shutdown
+}
+
+// writeCheck responds 200 if check passed and 503 otherwise
+func writeCheck(w http.ResponseWriter, log *slog.Logger, err error) {
+	if err != nil {
+		log.Warn("health check failed", "err", err.Error())
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok"))
+}
@@ -4,118 +4,174 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"log/slog"
+	"os"
 
 	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/producer"
 	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
 	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/health"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/input"
 )
 
 type sendCloser interface {
-	Send(msg dto.User, topic string, key string) error
-	Close()
+	Send(ctx context.Context, msg dto.User, topic string) error
+	Close() error
+	health.Checker
 }
 
 type App struct {
 	ServerProducer sendCloser
-	log            *slog.Logger
-	Cfg            *config.Config
+	source         input.Source[dto.User]
+	// file source reads from, nil for stdin
+	file *os.File
+	log  *slog.Logger
+	Cfg  *config.Config
 }
 
 func New(cfg *config.Config, log *slog.Logger) (*App, error) {
+	var r io.Reader = os.Stdin
+	var file *os.File
+	if cfg.Input.File != "" && cfg.Input.File != "-" {
+		var err error
+		file, err = os.Open(cfg.Input.File)
+		if err != nil {
+			return nil, fmt.Errorf("opening input file: %w", err)
+		}
+		r = file
+	}
+	source, err := input.New[dto.User](cfg.Input.Format, r, os.Stdout, cfg.Input.CSVMapping)
+	if err != nil {
+		return nil, errors.Join(err, closeFile(file))
+	}
 
-	prod, err := producer.New(cfg, log)
+	prod, err := producer.New[dto.User](cfg, log)
 	if err != nil {
-		return nil, err
+		return nil, errors.Join(err, closeFile(file))
 	}
 
 	return &App{
 		ServerProducer: prod,
+		source:         source,
+		file:           file,
 		log:            log,
 		Cfg:            cfg,
 	}, nil
 }
 
-func (a *App) Start(ctx context.Context) {
+func closeFile(file *os.File) error {
+	if file == nil {
+		return nil
+	}
+	return file.Close()
+}
+
+// record is a result of reading one message from input
+type record struct {
+	value dto.User
+	err   error
+}
+
+// Start sends messages read from input until ctx is done or input is over.
+// Invalid records are logged and skipped. It returns error when input can't
+// be read or producer can't send messages anymore.
+// Messages already passed to kafka client are delivered by Stop.
+func (a *App) Start(ctx context.Context) error {
 	a.log.Info("producer starts")
+	records := make(chan record)
+	next := make(chan struct{})
+	// reading stdin can't be interrupted, so it's done in background
+	go func() {
+		defer close(records)
+		for {
+			value, err := a.source.Next()
+			select {
+			case records <- record{value: value, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil && !errors.Is(err, input.ErrInvalidRecord) {
+				return
+			}
+			select {
+			case <-next:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 	for {
 		select {
 		case <-ctx.Done():
-			return
-		default:
-			value, err := a.readInput()
-			if err != nil {
-				log.Fatal(err.Error())
+			a.log.Info("producer stops reading input")
+			return nil
+		case in := <-records:
+			if errors.Is(in.err, io.EOF) {
+				a.log.Info("input is over")
+				return nil
 			}
-			err = a.ServerProducer.Send(*value, a.Cfg.Kafka.Topic, "53")
+			if errors.Is(in.err, input.ErrInvalidRecord) {
+				a.log.Warn("skipping invalid record", "err", in.err.Error())
+				select {
+				case next <- struct{}{}:
+				case <-ctx.Done():
+					a.log.Info("producer stops reading input")
+					return nil
+				}
+				continue
+			}
+			if in.err != nil {
+				return fmt.Errorf("reading input failed: %w", in.err)
+			}
+			err := a.ServerProducer.Send(ctx, in.value, a.Cfg.Kafka.Topic)
 			if err != nil {
-				log.Fatal(err.Error())
+				if !isMessageError(err) {
+					return err
+				}
+				// the message is lost, but the producer is still able to send next ones
+				a.log.Error("sending message failed", "err", err.Error())
+			}
+			// reader may have returned on shutdown, so nobody waits for next
+			select {
+			case next <- struct{}{}:
+			case <-ctx.Done():
+				a.log.Info("producer stops reading input")
+				return nil
 			}
 		}
 	}
 }
 
-func (a *App) Stop() {
-	a.log.Info("close kafka client")
-	a.ServerProducer.Close()
-}
-
-func (a *App) GetConfig() string {
-	return a.Cfg.String()
+// isMessageError reports whether err relates to a single message only
+func isMessageError(err error) bool {
+	return errors.Is(err, producer.ErrValidation) ||
+		errors.Is(err, producer.ErrSerialization) ||
+		errors.Is(err, producer.ErrQueueFull) ||
+		errors.Is(err, producer.ErrMessageTooLarge) ||
+		errors.Is(err, producer.ErrUnknownTopic)
 }
 
-func (a *App) readInput() (*dto.User, error) {
-	// TODO: it would be better to have data validation here
-
-	var (
-		name           string
-		favoriteNumber int64
-		favoriteColor  string
-		command        string
-	)
-	for {
-		fmt.Print("Command: ")
-		_, err := fmt.Scanln(&command)
-		if err != nil {
-			a.log.Error(err.Error())
-			return nil, errors.New("reading command failed")
-		}
-
-		if command == "exit" {
-			return nil, errors.New("terminate")
-		}
-		if command == "send" {
-			break
-		}
-		a.log.Info("Введите команду exit для выхода или send для отправки сообщений")
-	}
-
-	fmt.Print("Enter name: ")
-	_, err := fmt.Scanln(&name)
-	if err != nil {
-		a.log.Error(err.Error())
-		return nil, errors.New("reading name failed")
-	}
-
-	fmt.Print("Enter favorite number: ")
-	_, err = fmt.Scanln(&favoriteNumber)
+// Stop waits for delivery of sent messages and closes kafka client and input file
+func (a *App) Stop() error {
+	a.log.Info("close kafka client")
+	err := errors.Join(a.ServerProducer.Close(), closeFile(a.file))
 	if err != nil {
 		a.log.Error(err.Error())
-		return nil, errors.New("reading favorite number failed")
 	}
+	return err
+}
 
-	fmt.Print("Enter favorite color: ")
-	_, err = fmt.Scanln(&favoriteColor)
-	if err != nil {
-		return nil, errors.New("reading favorite color failed")
-	}
+// Live reports whether the application is alive
+func (a *App) Live() error {
+	return a.ServerProducer.Live()
+}
 
-	value := &dto.User{
-		Name:            name,
-		Favorite_number: favoriteNumber,
-		Favorite_color:  favoriteColor,
-	}
+// Ready reports whether the application is able to send messages
+func (a *App) Ready(ctx context.Context) error {
+	return a.ServerProducer.Ready(ctx)
+}
 
-	return value, nil
+func (a *App) GetConfig() string {
+	return a.Cfg.String()
 }
@@ -0,0 +1,98 @@
+package transformer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	consumer "github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/consumer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/producer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/health"
+)
+
+type transformCloser interface {
+	Transform(ctx context.Context) error
+	Close() error
+	health.Checker
+}
+
+type App struct {
+	Transformer transformCloser
+	log         *slog.Logger
+	Cfg         *config.Config
+}
+
+func New(cfg *config.Config, log *slog.Logger) (*App, error) {
+	a := &App{
+		log: log,
+		Cfg: cfg,
+	}
+	t, err := consumer.NewTransformer[dto.User, dto.User](context.Background(), cfg, log, a.transform)
+	if err != nil {
+		return nil, err
+	}
+	a.Transformer = t
+	return a, nil
+}
+
+// Start transforms messages until ctx is done,
+// it returns error when transformer has to be restarted
+func (a *App) Start(ctx context.Context) error {
+	a.log.Info("transformer starts")
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+			err := a.Transformer.Transform(ctx)
+			if err != nil {
+				if !isRecoverable(err) {
+					return err
+				}
+				// the batch will be consumed again
+				a.log.Error("transforming batch failed", "err", err.Error())
+			}
+		}
+	}
+}
+
+// isRecoverable reports whether transforming may go on after err,
+// fatal producer errors (e.g. producer is fenced) require restart
+func isRecoverable(err error) bool {
+	return !errors.Is(err, producer.ErrFatal)
+}
+
+// transform normalizes user names and colors
+func (a *App) transform(_ context.Context, msg consumer.Record[dto.User]) ([]dto.User, error) {
+	user := msg.Value
+	user.Name = strings.TrimSpace(user.Name)
+	user.Favorite_color = strings.ToLower(strings.TrimSpace(user.Favorite_color))
+	return []dto.User{user}, nil
+}
+
+// Stop closes kafka clients, it must be called after Start returned
+func (a *App) Stop() error {
+	a.log.Info("close kafka client")
+	err := a.Transformer.Close()
+	if err != nil {
+		a.log.Error(err.Error())
+	}
+	return err
+}
+
+// Live reports whether poll loop of the transformer is alive
+func (a *App) Live() error {
+	return a.Transformer.Live()
+}
+
+// Ready reports whether the application is able to transform messages
+func (a *App) Ready(ctx context.Context) error {
+	return a.Transformer.Ready(ctx)
+}
+
+func (a *App) GetConfig() string {
+	return a.Cfg.String()
+}
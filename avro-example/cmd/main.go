@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 	"os/signal"
 	"syscall"
 
@@ -17,6 +18,18 @@ func main() {
 	}
 	log.Printf("application starts with cfg -> %s \n", application.GetConfig())
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
-	defer cancel()
-	application.Start(ctx)
+	startErr := application.Start(ctx)
+	cancel()
+	if startErr != nil {
+		log.Printf("application failed: %s \n", startErr)
+	}
+	// in-flight work is drained by Stop, nonzero exit code reports that it failed
+	if err := application.Stop(); err != nil {
+		log.Printf("graceful shutdown failed: %s \n", err)
+		os.Exit(1)
+	}
+	if startErr != nil {
+		os.Exit(1)
+	}
+	log.Println("application stopped")
 }
@@ -0,0 +1,100 @@
+package avroschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var ErrUnsupportedType = errors.New("unsupported avro type")
+
+// TypeUnion is Type of union fields
+const TypeUnion = "union"
+
+// Field is a top level field of avro record schema
+type Field struct {
+	Name string
+	// Type is a name of primitive or named type, complex types (e.g. enum,
+	// fixed or long with logical type) are named by their "type" attribute,
+	// it's TypeUnion for unions
+	Type string
+	// Union lists types of union members named the same way, nil unless Type is TypeUnion
+	Union      []string
+	HasDefault bool
+	// Validation is custom "validation" attribute, nil if field has none,
+	// e.g. {"name": "name", "type": "string", "validation": {"notEmpty": true}}
+	Validation json.RawMessage
+}
+
+// Nullable reports whether field is union with null
+func (f Field) Nullable() bool {
+	return slices.Contains(f.Union, "null")
+}
+
+// fieldSchema is a field of avro record schema json
+type fieldSchema struct {
+	Name       string          `json:"name"`
+	Type       json.RawMessage `json:"type"`
+	Default    json.RawMessage `json:"default"`
+	Validation json.RawMessage `json:"validation"`
+}
+
+// Parse returns top level fields of avro record schema json
+func Parse(schema string) ([]Field, error) {
+	var record struct {
+		Fields []fieldSchema `json:"fields"`
+	}
+	err := json.Unmarshal([]byte(schema), &record)
+	if err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+	fields := make([]Field, 0, len(record.Fields))
+	for _, f := range record.Fields {
+		typ, union, err := parseType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		fields = append(fields, Field{
+			Name:       f.Name,
+			Type:       typ,
+			Union:      union,
+			HasDefault: f.Default != nil,
+			Validation: f.Validation,
+		})
+	}
+	return fields, nil
+}
+
+// parseType returns name of type or TypeUnion with names of union members
+func parseType(raw json.RawMessage) (string, []string, error) {
+	var name string
+	if json.Unmarshal(raw, &name) == nil {
+		return name, nil, nil
+	}
+	var members []json.RawMessage
+	if json.Unmarshal(raw, &members) == nil {
+		union := make([]string, 0, len(members))
+		for _, member := range members {
+			memberType, memberUnion, err := parseType(member)
+			if err != nil {
+				return "", nil, err
+			}
+			if memberUnion != nil {
+				return "", nil, fmt.Errorf("%w: nested union", ErrUnsupportedType)
+			}
+			union = append(union, memberType)
+		}
+		return TypeUnion, union, nil
+	}
+	// complex type, e.g. {"type": "enum", "symbols": [...]}
+	// or {"type": "long", "logicalType": "timestamp-millis"}
+	var complexType struct {
+		Type string `json:"type"`
+	}
+	err := json.Unmarshal(raw, &complexType)
+	if err != nil || complexType.Type == "" {
+		return "", nil, fmt.Errorf("%w: %s", ErrUnsupportedType, raw)
+	}
+	return complexType.Type, nil, nil
+}
@@ -0,0 +1,70 @@
+package avroschema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+)
+
+func TestParse(t *testing.T) {
+	schema := `{
+		"type": "record",
+		"name": "Event",
+		"fields": [
+			{"name": "id", "type": "string", "validation": {"notEmpty": true}},
+			{"name": "note", "type": ["null", "string"], "default": null},
+			{"name": "at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+			{"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A", "B"]}}
+		]
+	}`
+	fields, err := Parse(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Field{
+		{Name: "id", Type: "string", Validation: []byte(`{"notEmpty": true}`)},
+		{Name: "note", Type: TypeUnion, Union: []string{"null", "string"}, HasDefault: true},
+		{Name: "at", Type: "long"},
+		{Name: "kind", Type: "enum"},
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("got %+v, want %+v", fields, want)
+	}
+	if !fields[1].Nullable() || fields[0].Nullable() {
+		t.Errorf("only note field is nullable")
+	}
+
+	// generated records embed the schema they are parsed from
+	fields, err = Parse(new(dto.User).Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 3 || fields[0].Name != "name" {
+		t.Errorf("got %+v for User schema", fields)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		err    error
+	}{
+		{"not json", `{`, nil},
+		{"nested union", `{"fields": [{"name": "f", "type": ["null", ["int", "long"]]}]}`, ErrUnsupportedType},
+		{"no type", `{"fields": [{"name": "f", "type": {"items": "int"}}]}`, ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.schema)
+			if err == nil {
+				t.Fatal("schema is parsed")
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
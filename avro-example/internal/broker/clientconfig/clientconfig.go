@@ -0,0 +1,195 @@
+package clientconfig
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+)
+
+// Producer returns librdkafka config of producer
+func Producer(cfg *config.Config) (*kafka.ConfigMap, error) {
+	configMap := &kafka.ConfigMap{
+		"bootstrap.servers": cfg.Kafka.KafkaURL,
+	}
+	// explicit partition is set by producer for every message
+	if cfg.Kafka.Partitioner != config.PartitionerExplicit {
+		err := configMap.SetKey("partitioner", cfg.Kafka.Partitioner)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := idempotence(configMap, cfg.Idempotent())
+	if err != nil {
+		return nil, err
+	}
+	err = security(configMap, &cfg.Kafka.Security)
+	if err != nil {
+		return nil, err
+	}
+	err = statistics(configMap, &cfg.Monitoring)
+	if err != nil {
+		return nil, err
+	}
+	err = merge(configMap, cfg.Producer)
+	if err != nil {
+		return nil, err
+	}
+	return configMap, nil
+}
+
+// Consumer returns librdkafka config of consumer from group groupID
+func Consumer(cfg *config.Config, groupID string) (*kafka.ConfigMap, error) {
+	configMap := &kafka.ConfigMap{
+		"bootstrap.servers":  cfg.Kafka.KafkaURL,
+		"group.id":           groupID,
+		"session.timeout.ms": int(cfg.Kafka.SessionTimeout.Milliseconds()),
+		"auto.offset.reset":  cfg.Kafka.AutoOffsetReset,
+		// partitions are assigned incrementally with cooperative-sticky assignor
+		"partition.assignment.strategy": cfg.Kafka.AssignmentStrategy,
+		// offsets are stored and committed manually after message is handled,
+		// that gives at-least-once semantics
+		"enable.auto.commit":       false,
+		"enable.auto.offset.store": false,
+	}
+	err := security(configMap, &cfg.Kafka.Security)
+	if err != nil {
+		return nil, err
+	}
+	err = statistics(configMap, &cfg.Monitoring)
+	if err != nil {
+		return nil, err
+	}
+	err = merge(configMap, cfg.Consumer)
+	if err != nil {
+		return nil, err
+	}
+	return configMap, nil
+}
+
+// SchemaRegistry returns schema registry client config
+func SchemaRegistry(cfg *config.Config) *schemaregistry.Config {
+	auth := cfg.Kafka.SchemaRegistryAuth
+	var srConfig *schemaregistry.Config
+	switch {
+	case auth.Username != "":
+		srConfig = schemaregistry.NewConfigWithBasicAuthentication(
+			cfg.Kafka.SchemaRegistryURL, auth.Username, auth.Password,
+		)
+	case auth.BearerToken != "":
+		srConfig = schemaregistry.NewConfigWithBearerAuthentication(
+			cfg.Kafka.SchemaRegistryURL, auth.BearerToken, auth.LogicalCluster, auth.IdentityPoolID,
+		)
+	default:
+		srConfig = schemaregistry.NewConfig(cfg.Kafka.SchemaRegistryURL)
+	}
+	srConfig.SslCaLocation = auth.CALocation
+	srConfig.SslCertificateLocation = auth.CertLocation
+	srConfig.SslKeyLocation = auth.KeyLocation
+	return srConfig
+}
+
+// mockRegistries are schema registry mocks by url, so clients of the same
+// mock:// url share schemas as they do with real schema registry
+var (
+	mockRegistriesMu sync.Mutex
+	mockRegistries   = make(map[string]schemaregistry.Client)
+)
+
+// SchemaRegistryClient returns schema registry client,
+// clients of the same mock:// url share one mock registry
+func SchemaRegistryClient(cfg *config.Config) (schemaregistry.Client, error) {
+	url := cfg.Kafka.SchemaRegistryURL
+	if !strings.HasPrefix(url, "mock://") {
+		return schemaregistry.NewClient(SchemaRegistry(cfg))
+	}
+	mockRegistriesMu.Lock()
+	defer mockRegistriesMu.Unlock()
+	client, ok := mockRegistries[url]
+	if ok {
+		return client, nil
+	}
+	client, err := schemaregistry.NewClient(SchemaRegistry(cfg))
+	if err != nil {
+		return nil, err
+	}
+	mockRegistries[url] = client
+	return client, nil
+}
+
+// security sets authentication and encryption properties
+func security(configMap *kafka.ConfigMap, sec *config.SecurityConfig) error {
+	properties := map[string]string{
+		"security.protocol": strings.ToLower(sec.Protocol),
+		// ssl.* are ignored by librdkafka for PLAINTEXT and SASL_PLAINTEXT
+		"ssl.ca.location":          sec.SSL.CALocation,
+		"ssl.certificate.location": sec.SSL.CertLocation,
+		"ssl.key.location":         sec.SSL.KeyLocation,
+		"ssl.key.password":         sec.SSL.KeyPassword,
+	}
+	if sec.IsSASL() {
+		mechanism := strings.ToUpper(sec.SASL.Mechanism)
+		properties["sasl.mechanisms"] = mechanism
+		if mechanism == "OAUTHBEARER" {
+			properties["sasl.oauthbearer.method"] = "oidc"
+			properties["sasl.oauthbearer.token.endpoint.url"] = sec.SASL.TokenEndpointURL
+			properties["sasl.oauthbearer.client.id"] = sec.SASL.ClientID
+			properties["sasl.oauthbearer.client.secret"] = sec.SASL.ClientSecret
+			properties["sasl.oauthbearer.scope"] = sec.SASL.Scope
+		} else {
+			properties["sasl.username"] = sec.SASL.Username
+			properties["sasl.password"] = sec.SASL.Password
+		}
+	}
+	for key, value := range properties {
+		if value == "" {
+			continue
+		}
+		err := configMap.SetKey(key, value)
+		if err != nil {
+			return fmt.Errorf("setting %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// idempotence enables idempotent producer, so retries neither duplicate nor reorder messages
+func idempotence(configMap *kafka.ConfigMap, enabled bool) error {
+	if !enabled {
+		return nil
+	}
+	properties := map[string]any{
+		"enable.idempotence":                    true,
+		"acks":                                  "all",
+		"max.in.flight.requests.per.connection": 5,
+	}
+	for key, value := range properties {
+		err := configMap.SetKey(key, value)
+		if err != nil {
+			return fmt.Errorf("setting %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// statistics enables librdkafka statistics events used by metrics
+func statistics(configMap *kafka.ConfigMap, monitoring *config.MonitoringConfig) error {
+	if !monitoring.Enabled || monitoring.StatsInterval <= 0 {
+		return nil
+	}
+	return configMap.SetKey("statistics.interval.ms", int(monitoring.StatsInterval.Milliseconds()))
+}
+
+// merge sets pass-through properties from yaml config
+func merge(configMap *kafka.ConfigMap, properties config.Properties) error {
+	for key, value := range properties {
+		err := configMap.SetKey(key, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/clientconfig"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/producer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/metrics"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/tracing"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BatchHandler processes consumed records in batches. If HandleBatch returns an error,
+// the whole batch is delivered again or sent to the dead letter topic.
+// It has to finish within max.poll.interval.ms, as consumer doesn't poll meanwhile.
+type BatchHandler[T any] interface {
+	HandleBatch(ctx context.Context, msgs []Record[T]) error
+}
+
+// BatchHandlerFunc allows to use ordinary function as BatchHandler
+type BatchHandlerFunc[T any] func(ctx context.Context, msgs []Record[T]) error
+
+// HandleBatch calls f(ctx, msgs)
+func (f BatchHandlerFunc[T]) HandleBatch(ctx context.Context, msgs []Record[T]) error {
+	return f(ctx, msgs)
+}
+
+// NewBatch returns kafka consumer passing records to handler in batches
+// of up to batch.size records collected for at most batch.timeout.
+// Retry topics aren't supported in batch mode.
+func NewBatch[T any, PT dto.AvroRecord[T]](cfg *config.Config, log *slog.Logger, handler BatchHandler[T]) (*Broker[T, PT], error) {
+	var forwarder rawSendCloser
+	var err error
+	if cfg.Kafka.DLQ.Enabled {
+		forwarder, err = producer.New[T, PT](cfg, log)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	configMap, err := clientconfig.Consumer(cfg, cfg.Kafka.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	broker, err := newBroker[T, PT](cfg, log, configMap, nil, cfg.Kafka.Subscription(), 0, forwarder)
+	if err != nil {
+		return nil, err
+	}
+	broker.batchHandler = handler
+	broker.batchSize = cfg.Kafka.Batch.Size
+	broker.batchTimeout = cfg.Kafka.Batch.Timeout
+	return broker, nil
+}
+
+// consumeBatch collects batch of messages and handles them
+func (b *Broker[T, PT]) consumeBatch(ctx context.Context) error {
+	messages := b.collect(ctx)
+	if len(messages) == 0 {
+		return nil
+	}
+
+	err := b.processBatch(ctx, messages)
+	if err != nil {
+		// rewind, so the batch is consumed again
+		seekErr := b.rewind(messages)
+		if seekErr != nil {
+			return fmt.Errorf("%w, seek failed: %w", err, seekErr)
+		}
+		return err
+	}
+	return b.commitBatch(messages)
+}
+
+// collect polls messages until batch is full or batch timeout expires.
+// Collecting stops early when ctx is done, already polled messages are returned anyway.
+func (b *Broker[T, PT]) collect(ctx context.Context) []*kafka.Message {
+	deadline := time.Now().Add(b.batchTimeout)
+	b.pending = make([]*kafka.Message, 0, b.batchSize)
+	for len(b.pending) < b.batchSize && ctx.Err() == nil {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		b.lastPoll.Store(time.Now().UnixNano())
+		ev := b.consumer.Poll(min(b.pollTimeout, int(remaining.Milliseconds())))
+		if ev == nil {
+			continue
+		}
+		e, ok := ev.(*kafka.Message)
+		if !ok {
+			b.event(ev)
+			continue
+		}
+		metrics.MessagesConsumed.WithLabelValues(*e.TopicPartition.Topic).Inc()
+		b.pending = append(b.pending, e)
+	}
+	messages := b.pending
+	b.pending = nil
+	return messages
+}
+
+// dropPending drops messages of revoked partitions from the batch being collected,
+// they aren't handled, so their new owner consumes them from the committed offset
+func (b *Broker[T, PT]) dropPending(revoked []kafka.TopicPartition) {
+	gone := make(map[partition]bool, len(revoked))
+	for _, tp := range revoked {
+		gone[partition{topic: *tp.Topic, partition: tp.Partition}] = true
+	}
+	kept := b.pending[:0]
+	for _, e := range b.pending {
+		if !gone[partition{topic: *e.TopicPartition.Topic, partition: e.TopicPartition.Partition}] {
+			kept = append(kept, e)
+		}
+	}
+	if dropped := len(b.pending) - len(kept); dropped > 0 {
+		b.log.Info("messages of revoked partitions dropped from batch", "count", dropped)
+	}
+	b.pending = kept
+}
+
+// processBatch deserializes messages and passes them to the batch handler.
+// Messages which can't be deserialized and failed batches are sent
+// to the dead letter topic, if it is enabled.
+func (b *Broker[T, PT]) processBatch(ctx context.Context, messages []*kafka.Message) (err error) {
+	// batch span is linked to spans of all producers
+	links := make([]trace.Link, 0, len(messages))
+	for _, e := range messages {
+		link := trace.LinkFromContext(extract(ctx, e))
+		if link.SpanContext.IsValid() {
+			links = append(links, link)
+		}
+	}
+	ctx, span := b.tracer.Start(
+		ctx,
+		"process batch",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingOperationName("process"),
+			semconv.MessagingBatchMessageCount(len(messages)),
+			semconv.MessagingKafkaConsumerGroup(b.groupID),
+		),
+	)
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
+	records := make([]Record[T], 0, len(messages))
+	deserialized := make([]*kafka.Message, 0, len(messages))
+	for _, e := range messages {
+		record, err := b.deserialize(e)
+		if err != nil {
+			err = b.reroute(ctx, e, err)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		records = append(records, record)
+		deserialized = append(deserialized, e)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	err = b.batchHandler.HandleBatch(context.WithoutCancel(ctx), records)
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	metrics.BatchSize.Observe(float64(len(records)))
+	metrics.BatchDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("%w: %w", ErrHandle, err)
+	for _, e := range deserialized {
+		rerouteErr := b.reroute(ctx, e, err)
+		if rerouteErr != nil {
+			return rerouteErr
+		}
+	}
+	return nil
+}
+
+// commitBatch synchronously commits the highest offsets per partition of messages.
+// Partitions revoked while the batch was collected or handled are skipped,
+// they may be already consumed by other group member.
+func (b *Broker[T, PT]) commitBatch(messages []*kafka.Message) error {
+	offsets, err := b.assigned(nextOffsets(messages))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCommit, err)
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+	committed, err := b.consumer.CommitOffsets(offsets)
+	if err != nil {
+		metrics.CommitFailures.Inc()
+		return fmt.Errorf("%w: %w", ErrCommit, err)
+	}
+	b.log.Debug("offsets committed", "offsets", committed)
+	b.lastCommit = time.Now()
+	return nil
+}
+
+// assigned returns offsets of partitions currently assigned to consumer
+func (b *Broker[T, PT]) assigned(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+	assignment, err := b.consumer.Assignment()
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[partition]bool, len(assignment))
+	for _, tp := range assignment {
+		current[partition{topic: *tp.Topic, partition: tp.Partition}] = true
+	}
+	filtered := offsets[:0]
+	for _, tp := range offsets {
+		if current[partition{topic: *tp.Topic, partition: tp.Partition}] {
+			filtered = append(filtered, tp)
+		}
+	}
+	return filtered, nil
+}
+
+// nextOffsets returns offsets to commit after messages are handled,
+// that is the highest offset plus one per partition
+func nextOffsets(messages []*kafka.Message) []kafka.TopicPartition {
+	highest := make(map[partition]kafka.TopicPartition)
+	for _, e := range messages {
+		p := partition{topic: *e.TopicPartition.Topic, partition: e.TopicPartition.Partition}
+		if tp, ok := highest[p]; !ok || e.TopicPartition.Offset > tp.Offset {
+			highest[p] = e.TopicPartition
+		}
+	}
+	offsets := make([]kafka.TopicPartition, 0, len(highest))
+	for _, tp := range highest {
+		// committed offset is the offset of the next message to consume
+		tp.Offset++
+		offsets = append(offsets, tp)
+	}
+	return offsets
+}
+
+// rewind seeks every assigned partition of messages to its lowest offset among them
+func (b *Broker[T, PT]) rewind(messages []*kafka.Message) error {
+	lowest := make(map[partition]kafka.TopicPartition)
+	for _, e := range messages {
+		p := partition{topic: *e.TopicPartition.Topic, partition: e.TopicPartition.Partition}
+		if tp, ok := lowest[p]; !ok || e.TopicPartition.Offset < tp.Offset {
+			lowest[p] = e.TopicPartition
+		}
+	}
+	offsets := make([]kafka.TopicPartition, 0, len(lowest))
+	for _, tp := range lowest {
+		offsets = append(offsets, tp)
+	}
+	// revoked partitions are consumed from the committed offset by their new owner
+	offsets, err := b.assigned(offsets)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, tp := range offsets {
+		err := b.consumer.Seek(tp, 0)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// partition identifies topic partition, kafka.TopicPartition isn't comparable
+type partition struct {
+	topic     string
+	partition int32
+}
@@ -2,48 +2,178 @@ package broker
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
 
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/clientconfig"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/producer"
 	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
 	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/metrics"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/tracing"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
 	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde"
 	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/avro"
-	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type Message struct {
-	UUID    string
-	Balance int
-	Type    string
-	Comment string
+var (
+	ErrDeserialize = errors.New("deserializing message failed")
+	ErrHandle      = errors.New("handling message failed")
+	ErrCommit      = errors.New("committing offsets failed")
+	ErrDeadLetter  = errors.New("sending message to dead letter topic failed")
+)
+
+const tracerName = "github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/consumer"
+
+// Record is a consumed message together with its kafka metadata
+type Record[T any] struct {
+	Key       []byte
+	Value     T
+	Headers   []kafka.Header
+	Topic     string
+	Partition int32
+	Offset    kafka.Offset
+	Timestamp time.Time
 }
 
-type MessageReceived struct {
-	Msg Message
-	Ctx context.Context
-	Err error
+// Handler processes consumed records. If Handle returns an error,
+// the record is delivered again or sent to the dead letter topic.
+type Handler[T any] interface {
+	Handle(ctx context.Context, msg Record[T]) error
 }
 
-type Broker struct {
+// HandlerFunc allows to use ordinary function as Handler
+type HandlerFunc[T any] func(ctx context.Context, msg Record[T]) error
+
+// Handle calls f(ctx, msg)
+func (f HandlerFunc[T]) Handle(ctx context.Context, msg Record[T]) error {
+	return f(ctx, msg)
+}
+
+type rawSendCloser interface {
+	SendRaw(ctx context.Context, msg *kafka.Message) (kafka.TopicPartition, error)
+	Close() error
+}
+
+// Broker consumes gogen-avro records of type T, PT is inferred as *T.
+type Broker[T any, PT dto.AvroRecord[T]] struct {
 	consumer     *kafka.Consumer
 	deserializer serde.Deserializer
+	handler      Handler[T]
 	log          *slog.Logger
+	// forwarder is nil when both dead letter and retry topics are disabled
+	forwarder  rawSendCloser
+	dlqEnabled bool
+	dlqSuffix  string
+	retry      config.RetryConfig
+	// stage is 0 for the source topic consumer and
+	// retry attempt number for retry topic consumers
+	stage  int
+	stages []*Broker[T, PT]
+	// poll timeout in milliseconds
+	pollTimeout int
+	groupID     string
+	tracer      trace.Tracer
+	registry    schemaregistry.Client
+	// unix nano time of the last Consume call, poll loop is stale
+	// when it is older than staleness
+	lastPoll  atomic.Int64
+	staleness time.Duration
+	// offsets are committed after commitBatchSize handled messages
+	// or commitInterval, whichever comes first
+	commitBatchSize int
+	commitInterval  time.Duration
+	uncommitted     int
+	lastCommit      time.Time
+	// batchHandler is set in batch mode instead of handler
+	batchHandler BatchHandler[T]
+	batchSize    int
+	batchTimeout time.Duration
+	// pending is the batch being collected, messages of revoked partitions are dropped from it
+	pending []*kafka.Message
+	// listener is notified about partition assignment changes, it may be nil
+	listener RebalanceListener
+	// workers is set in concurrent mode, each assigned partition
+	// is handled by keyWorkers goroutines
+	workers    map[partition]*partitionWorkers
+	keyWorkers int
+	// partition is paused when queueSize its messages are in flight
+	queueSize int
+}
+
+// New returns kafka consumer with schema registry.
+// When retries are enabled, consumers of retry topics are created as well,
+// see RetryStages.
+func New[T any, PT dto.AvroRecord[T]](cfg *config.Config, log *slog.Logger, handler Handler[T]) (*Broker[T, PT], error) {
+	// forwarder sends messages to retry and dead letter topics
+	var forwarder rawSendCloser
+	var err error
+	if cfg.Kafka.DLQ.Enabled || cfg.Kafka.Retry.Enabled {
+		forwarder, err = producer.New[T, PT](cfg, log)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	topics := cfg.Kafka.Subscription()
+	groupID := cfg.Kafka.GroupID
+
+	configMap, err := clientconfig.Consumer(cfg, groupID)
+	if err != nil {
+		return nil, err
+	}
+	broker, err := newBroker[T, PT](cfg, log, configMap, handler, topics, 0, forwarder)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Kafka.Retry.Enabled {
+		return broker, nil
+	}
+	for stage := 1; stage <= cfg.Kafka.Retry.Attempts; stage++ {
+		configMap, err := clientconfig.Consumer(cfg, fmt.Sprintf("%s.retry.%d", groupID, stage))
+		if err != nil {
+			return nil, err
+		}
+		stageBroker, err := newBroker[T, PT](
+			cfg, log, configMap, handler, retrySubscription(topics, stage), stage, forwarder,
+		)
+		if err != nil {
+			return nil, err
+		}
+		broker.stages = append(broker.stages, stageBroker)
+	}
+	return broker, nil
 }
 
-// New returns kafka consumer with schema registry
-func New(cfg *config.Config, log *slog.Logger) (*Broker, error) {
-	confluentConsumer, err := kafka.NewConsumer(&kafka.ConfigMap{
-		"bootstrap.servers":  cfg.Kafka.KafkaURL,
-		"group.id":           "1",
-		"session.timeout.ms": 6000,
-		"auto.offset.reset":  "earliest"})
+// newBroker returns kafka consumer of the given topics created with configMap,
+// stage is 0 for the source topics and retry attempt number for retry topics
+func newBroker[T any, PT dto.AvroRecord[T]](
+	cfg *config.Config,
+	log *slog.Logger,
+	configMap *kafka.ConfigMap,
+	handler Handler[T],
+	topics []string,
+	stage int,
+	forwarder rawSendCloser,
+) (*Broker[T, PT], error) {
+	groupID, err := configMap.Get("group.id", "")
+	if err != nil {
+		return nil, err
+	}
+	confluentConsumer, err := kafka.NewConsumer(configMap)
 	if err != nil {
 		return nil, err
 	}
 
-	client, err := schemaregistry.NewClient(schemaregistry.NewConfig(cfg.Kafka.SchemaRegistryURL))
+	client, err := clientconfig.SchemaRegistryClient(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -53,70 +183,251 @@ func New(cfg *config.Config, log *slog.Logger) (*Broker, error) {
 		return nil, err
 	}
 
-	// TODO topic get from config
-	err = confluentConsumer.Subscribe("users", nil)
-
-	broker := &Broker{
+	broker := &Broker[T, PT]{
 		consumer:     confluentConsumer,
 		deserializer: deser,
-		log:          log,
+		handler:      handler,
+		log:          log.With("subscription", topics),
+		forwarder:    forwarder,
+		dlqEnabled:   cfg.Kafka.DLQ.Enabled,
+		dlqSuffix:    cfg.Kafka.DLQ.Suffix,
+		retry:        cfg.Kafka.Retry,
+		stage:        stage,
+		pollTimeout:  int(cfg.Kafka.PollTimeout.Milliseconds()),
+		groupID:      groupID.(string),
+		tracer:       otel.Tracer(tracerName),
+		registry:     client,
+		staleness:    cfg.Monitoring.PollStaleness,
+
+		commitBatchSize: cfg.Kafka.CommitBatchSize,
+		commitInterval:  cfg.Kafka.CommitInterval,
+		lastCommit:      time.Now(),
+	}
+	if stage > 0 && !cfg.Kafka.Workers.Enabled {
+		// retry consumer doesn't poll while waiting for retry time
+		broker.staleness += cfg.Kafka.Retry.MaxBackoff
+	}
+	if cfg.Kafka.Workers.Enabled {
+		broker.workers = make(map[partition]*partitionWorkers)
+		broker.keyWorkers = cfg.Kafka.Workers.PerPartition
+		broker.queueSize = cfg.Kafka.Workers.QueueSize
 	}
+
+	err = confluentConsumer.SubscribeTopics(topics, broker.rebalance)
+	if err != nil {
+		return nil, err
+	}
+	broker.lastPoll.Store(time.Now().UnixNano())
 	return broker, nil
 }
 
-// Close closes deserialization agent and kafka consumer
+// Close commits stored offsets, closes deserialization agent and kafka consumer.
+// Retry stages have to be closed before the source consumer.
 // WARNING: Consume method need to be finished before.
 // https://github.com/confluentinc/confluent-kafka-go/issues/136#issuecomment-586166364
-func (b *Broker) Close() error {
+func (b *Broker[T, PT]) Close() error {
+	var workersErr error
+	if b.workers != nil {
+		workersErr = b.storeCompleted(b.stopWorkers(nil))
+	}
+	commitErr := errors.Join(workersErr, b.commit())
+	if commitErr != nil {
+		b.log.Error("final commit failed", "err", commitErr.Error())
+	}
 	b.deserializer.Close()
+	// forwarder is shared with retry stages and owned by the source consumer
+	var forwarderErr error
+	if b.forwarder != nil && b.stage == 0 {
+		forwarderErr = b.forwarder.Close()
+	}
 	//https://docs.confluent.io/platform/current/clients/confluent-kafka-go/index.html#hdr-High_level_Consumer
+	// it leaves consumer group, so partitions are reassigned immediately
 	err := b.consumer.Close()
-	if err != nil {
-		return err
-	}
-	return nil
+	return errors.Join(commitErr, forwarderErr, err)
 }
 
-func (b *Broker) Consume() error {
-	//TODO: get timeout from config
-	ev := b.consumer.Poll(100)
+// Consume polls one event and passes received message to the handler.
+// Cancellation of ctx doesn't interrupt the handler of already polled message,
+// so it is finished on shutdown.
+// Offset of the message is stored only after the handler succeeds.
+// When the handler fails with a retryable error, the message is sent to
+// the next retry topic (if enabled). When the message can't be deserialized
+// or handled, it is sent to the dead letter topic (if enabled).
+// In both cases its offset is stored as well.
+// Otherwise, the partition is rewound to the message,
+// so it is consumed again by the next call.
+// In batch mode (see NewBatch) it collects and handles one batch instead.
+// In concurrent mode the message is passed to the worker of its partition.
+func (b *Broker[T, PT]) Consume(ctx context.Context) error {
+	if b.batchHandler != nil {
+		return b.consumeBatch(ctx)
+	}
+	if b.workers != nil {
+		return b.consumeConcurrently(ctx)
+	}
+	b.lastPoll.Store(time.Now().UnixNano())
+	ev := b.consumer.Poll(b.pollTimeout)
 	if ev == nil {
-		return nil
+		return b.commitIfDue()
 	}
 
 	switch e := ev.(type) {
 	case *kafka.Message:
-		var msg dto.User
-
-		err := b.deserializer.DeserializeInto(*e.TopicPartition.Topic, e.Value, &msg)
+		metrics.MessagesConsumed.WithLabelValues(*e.TopicPartition.Topic).Inc()
+		err := b.process(ctx, e)
 		if err != nil {
-			b.log.Error(
-				"Failed to deserialize payload",
-				"err", err.Error(),
-			)
-			return err
-		} else {
-			b.log.Info(
-				"Message received",
-				"topic", e.TopicPartition, "message", msg,
-			)
+			err = b.reroute(ctx, e, err)
 		}
-
-		if e.Headers != nil {
-			headers := propagation.MapCarrier{}
-
-			for _, recordHeader := range e.Headers {
-				headers[recordHeader.Key] = string(recordHeader.Value)
+		if err != nil {
+			// rewind, so the message is consumed again
+			seekErr := b.consumer.Seek(e.TopicPartition, 0)
+			if seekErr != nil {
+				return fmt.Errorf("%w, seek failed: %w", err, seekErr)
 			}
+			return err
+		}
+		_, err = b.consumer.StoreMessage(e)
+		if err != nil {
+			metrics.CommitFailures.Inc()
+			return fmt.Errorf("%w: %w", ErrCommit, err)
 		}
+		b.uncommitted++
+		return b.commitIfDue()
+	default:
+		b.event(ev)
+	}
+	return nil
+}
 
+// event handles polled events other than messages
+func (b *Broker[T, PT]) event(ev kafka.Event) {
+	switch e := ev.(type) {
 	case kafka.Error:
 		// Errors should generally be considered
 		// informational, the client will try to
 		// automatically recover.
 		b.log.Error("kafka.Error", "code", e.Code(), "err", e.Error())
+	case *kafka.Stats:
+		// statistics are emitted every statistics.interval.ms
+		err := metrics.ObserveConsumerStats(b.groupID, e.String())
+		if err != nil {
+			b.log.Error("parsing kafka statistics failed", "err", err.Error())
+		}
 	default:
 		b.log.Warn("Event:", "msg", e.String())
 	}
+}
+
+// process deserializes message and passes it to the handler.
+// Trace context is extracted from message headers into handler context.
+func (b *Broker[T, PT]) process(ctx context.Context, e *kafka.Message) (err error) {
+	if b.stage > 0 {
+		err := b.waitRetryAt(ctx, e)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrHandle, err)
+		}
+	}
+
+	// https://opentelemetry.io/docs/specs/semconv/messaging/kafka/
+	ctx, span := b.tracer.Start(
+		extract(ctx, e),
+		"process "+*e.TopicPartition.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingOperationName("process"),
+			semconv.MessagingDestinationName(*e.TopicPartition.Topic),
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(int(e.TopicPartition.Partition))),
+			semconv.MessagingKafkaMessageOffset(int(e.TopicPartition.Offset)),
+			semconv.MessagingKafkaMessageKey(string(e.Key)),
+			semconv.MessagingKafkaConsumerGroup(b.groupID),
+		),
+	)
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
+	record, err := b.deserialize(e)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = b.handler.Handle(context.WithoutCancel(ctx), record)
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	metrics.HandlerDuration.WithLabelValues(*e.TopicPartition.Topic, result).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrHandle, err)
+	}
+	return nil
+}
+
+// deserialize turns message into record
+func (b *Broker[T, PT]) deserialize(e *kafka.Message) (Record[T], error) {
+	var msg T
+
+	err := b.deserializer.DeserializeInto(*e.TopicPartition.Topic, e.Value, PT(&msg))
+	if err != nil {
+		b.log.Error(
+			"Failed to deserialize payload",
+			"err", err.Error(),
+		)
+		metrics.DeserializationFailures.WithLabelValues(*e.TopicPartition.Topic).Inc()
+		return Record[T]{}, fmt.Errorf("%w: %w", ErrDeserialize, err)
+	}
+	b.log.Debug(
+		"Message received",
+		"topic", e.TopicPartition, "schema", PT(&msg).SchemaName(),
+	)
+	return Record[T]{
+		Key:       e.Key,
+		Value:     msg,
+		Headers:   e.Headers,
+		Topic:     *e.TopicPartition.Topic,
+		Partition: e.TopicPartition.Partition,
+		Offset:    e.TopicPartition.Offset,
+		Timestamp: e.Timestamp,
+	}, nil
+}
+
+// extract returns ctx with trace context taken from message headers
+func extract(ctx context.Context, e *kafka.Message) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, tracing.HeadersCarrier{Headers: &e.Headers})
+}
+
+// commitIfDue commits stored offsets when batch size or interval is reached
+func (b *Broker[T, PT]) commitIfDue() error {
+	if b.uncommitted == 0 {
+		return nil
+	}
+	if b.uncommitted < b.commitBatchSize && time.Since(b.lastCommit) < b.commitInterval {
+		return nil
+	}
+	return b.commit()
+}
+
+// commit synchronously commits stored offsets
+func (b *Broker[T, PT]) commit() error {
+	if b.uncommitted == 0 {
+		return nil
+	}
+	offsets, err := b.consumer.Commit()
+	if err != nil {
+		var kafkaErr kafka.Error
+		if errors.As(err, &kafkaErr) && kafkaErr.Code() == kafka.ErrNoOffset {
+			// nothing was stored since the last commit
+			b.uncommitted = 0
+			return nil
+		}
+		metrics.CommitFailures.Inc()
+		return fmt.Errorf("%w: %w", ErrCommit, err)
+	}
+	b.log.Debug("offsets committed", "offsets", offsets)
+	b.uncommitted = 0
+	b.lastCommit = time.Now()
 	return nil
 }
@@ -0,0 +1,151 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/producer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/tracing"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTopic = "users"
+
+var testLog = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newTestCluster returns mock cluster with topic of the given number of partitions
+func newTestCluster(t *testing.T, partitions int) *kafka.MockCluster {
+	t.Helper()
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cluster.Close)
+	err = cluster.CreateTopic(testTopic, partitions, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cluster
+}
+
+// newTestExporter records spans of global tracer provider until the test ends
+func newTestExporter(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	_, err := tracing.New(context.Background(), config.TracingConfig{Exporter: "none"}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func TestTracePropagation(t *testing.T) {
+	exporter := newTestExporter(t)
+	cluster := newTestCluster(t, 1)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := fmt.Sprintf(
+		"kafka:\n  kafkaUrl: %q\n  schemaRegistryURL: \"mock://%s\"\n  topic: %q\n  groupId: %q\n",
+		cluster.BootstrapServers(), t.Name(), testTopic, t.Name(),
+	)
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadByPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the message is sent within a request span carrying baggage
+	member, err := baggage.NewMember("tenant", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bag, err := baggage.New(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, request := otel.Tracer("test").Start(baggage.ContextWithBaggage(context.Background(), bag), "request")
+	prod, err := producer.New[dto.User](cfg, testLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer prod.Close()
+	_, err = prod.SendSync(ctx, dto.User{Name: "Alice"}, testTopic, []byte("Alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.End()
+
+	var handled bool
+	var handlerSpan trace.SpanContext
+	var tenant string
+	handler := HandlerFunc[dto.User](func(ctx context.Context, _ Record[dto.User]) error {
+		handled = true
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		tenant = baggage.FromContext(ctx).Member("tenant").Value()
+		return nil
+	})
+	b, err := New[dto.User](cfg, testLog, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	deadline := time.Now().Add(30 * time.Second)
+	for !handled {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for message")
+		}
+		err := b.Consume(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	publish, ok := spans["publish "+testTopic]
+	if !ok || publish.SpanKind != trace.SpanKindProducer {
+		t.Fatalf("producer span isn't recorded, got %v", spans)
+	}
+	process, ok := spans["process "+testTopic]
+	if !ok || process.SpanKind != trace.SpanKindConsumer {
+		t.Fatalf("consumer span isn't recorded, got %v", spans)
+	}
+	if publish.Parent.SpanID() != spans["request"].SpanContext.SpanID() {
+		t.Errorf("producer span isn't a child of request span")
+	}
+	// consumer span continues the trace of the producer span
+	if process.SpanContext.TraceID() != publish.SpanContext.TraceID() ||
+		process.Parent.SpanID() != publish.SpanContext.SpanID() {
+		t.Errorf("consumer span has parent %s, want producer span %s", process.Parent.SpanID(), publish.SpanContext.SpanID())
+	}
+	if handlerSpan.SpanID() != process.SpanContext.SpanID() {
+		t.Errorf("handler context has span %s, want consumer span %s", handlerSpan.SpanID(), process.SpanContext.SpanID())
+	}
+	if tenant != "acme" {
+		t.Errorf("handler got baggage tenant %q, want %q", tenant, "acme")
+	}
+}
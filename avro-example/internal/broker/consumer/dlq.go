@@ -0,0 +1,54 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// headers added to messages sent to the dead letter topic
+const (
+	HeaderDLQError           = "dlq.error"
+	HeaderDLQSourceTopic     = "dlq.source.topic"
+	HeaderDLQSourcePartition = "dlq.source.partition"
+	HeaderDLQSourceOffset    = "dlq.source.offset"
+	HeaderDLQSourceTimestamp = "dlq.source.timestamp"
+	HeaderDLQTimestamp       = "dlq.timestamp"
+)
+
+// deadLetter re-produces original bytes, key and headers of e to
+// <topic><dlqSuffix> with extra headers describing the failure.
+// Messages from retry topics go to dead letter topic of the source topic.
+func (b *Broker[T, PT]) deadLetter(ctx context.Context, e *kafka.Message, cause error) error {
+	topic := sourceTopic(e) + b.dlqSuffix
+
+	headers := make([]kafka.Header, 0, len(e.Headers)+6)
+	headers = append(headers, e.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderDLQError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderDLQSourceTopic, Value: []byte(*e.TopicPartition.Topic)},
+		kafka.Header{Key: HeaderDLQSourcePartition, Value: []byte(strconv.Itoa(int(e.TopicPartition.Partition)))},
+		kafka.Header{Key: HeaderDLQSourceOffset, Value: []byte(e.TopicPartition.Offset.String())},
+		kafka.Header{Key: HeaderDLQSourceTimestamp, Value: []byte(e.Timestamp.Format(time.RFC3339Nano))},
+		kafka.Header{Key: HeaderDLQTimestamp, Value: []byte(time.Now().Format(time.RFC3339Nano))},
+	)
+
+	// see retryLater
+	tp, err := b.forwarder.SendRaw(context.WithoutCancel(ctx), &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            e.Key,
+		Value:          e.Value,
+		Headers:        headers,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w: %w", ErrDeadLetter, err, cause)
+	}
+	b.log.Warn(
+		"message sent to dead letter topic",
+		"source", e.TopicPartition, "dlq", tp, "err", cause.Error(),
+	)
+	return nil
+}
@@ -0,0 +1,47 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/health"
+)
+
+var (
+	ErrNoAssignment = errors.New("no partitions are assigned")
+	ErrPollStale    = errors.New("poll loop is stale")
+)
+
+// Live reports whether poll loop is alive, i.e. Consume was called
+// not longer than staleness ago
+func (b *Broker[T, PT]) Live() error {
+	lastPoll := time.Unix(0, b.lastPoll.Load())
+	if since := time.Since(lastPoll); since > b.staleness {
+		return fmt.Errorf("%w: last poll %s ago", ErrPollStale, since.Round(time.Second))
+	}
+	return nil
+}
+
+// Ready reports whether the consumer is able to consume messages:
+// kafka and schema registry are reachable, partitions are assigned
+// and poll loop is alive
+func (b *Broker[T, PT]) Ready(ctx context.Context) error {
+	_, err := b.consumer.GetMetadata(nil, false, health.TimeoutMs(ctx))
+	if err != nil {
+		return fmt.Errorf("%w: %w", health.ErrKafkaUnreachable, err)
+	}
+	err = health.CheckSchemaRegistry(ctx, b.registry)
+	if err != nil {
+		return err
+	}
+	assignment, err := b.consumer.Assignment()
+	if err != nil {
+		return err
+	}
+	if len(assignment) == 0 {
+		return ErrNoAssignment
+	}
+	return b.Live()
+}
@@ -0,0 +1,99 @@
+package broker
+
+import (
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/metrics"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// rebalance protocol returned by kafka.Consumer.GetRebalanceProtocol
+// when cooperative-sticky assignor is used
+const cooperativeProtocol = "COOPERATIVE"
+
+// RebalanceListener is notified about partition assignment changes.
+// Its methods are called from Consume, so they must not block for long.
+type RebalanceListener interface {
+	// OnAssigned is called after partitions are assigned,
+	// with cooperative assignor partitions are the newly added ones only
+	OnAssigned(partitions []kafka.TopicPartition)
+	// OnRevoked is called after offsets of handled messages are committed,
+	// but before partitions are unassigned
+	OnRevoked(partitions []kafka.TopicPartition)
+}
+
+// SetRebalanceListener sets listener of partition assignment changes,
+// it has to be called before Consume. Retry stages have their own listeners.
+func (b *Broker[T, PT]) SetRebalanceListener(listener RebalanceListener) {
+	b.listener = listener
+}
+
+// rebalance is called by Poll on partition assignment and revocation.
+// Partitions are assigned and unassigned incrementally with cooperative assignor.
+func (b *Broker[T, PT]) rebalance(c *kafka.Consumer, ev kafka.Event) error {
+	protocol := c.GetRebalanceProtocol()
+	switch e := ev.(type) {
+	case kafka.AssignedPartitions:
+		var err error
+		if protocol == cooperativeProtocol {
+			err = c.IncrementalAssign(e.Partitions)
+		} else {
+			err = c.Assign(e.Partitions)
+		}
+		if err != nil {
+			b.log.Error("assigning partitions failed", "partitions", e.Partitions, "err", err.Error())
+			return err
+		}
+		b.log.Info("partitions assigned", "protocol", protocol, "partitions", e.Partitions)
+		metrics.Rebalances.WithLabelValues(b.groupID, "assigned").Inc()
+		metrics.AssignedPartitions.WithLabelValues(b.groupID).Add(float64(len(e.Partitions)))
+		if b.listener != nil {
+			b.listener.OnAssigned(e.Partitions)
+		}
+
+	case kafka.RevokedPartitions:
+		lost := c.AssignmentLost()
+		b.log.Info("partitions revoked", "protocol", protocol, "partitions", e.Partitions, "lost", lost)
+		event := "revoked"
+		if lost {
+			event = "lost"
+		}
+		metrics.Rebalances.WithLabelValues(b.groupID, event).Inc()
+		metrics.AssignedPartitions.WithLabelValues(b.groupID).Sub(float64(len(e.Partitions)))
+		for _, tp := range e.Partitions {
+			metrics.DeleteConsumerLag(b.groupID, *tp.Topic, tp.Partition)
+		}
+
+		var stopped map[partition]*partitionWorkers
+		if b.workers != nil {
+			stopped = b.stopWorkers(e.Partitions)
+		}
+		if b.batchHandler != nil {
+			b.dropPending(e.Partitions)
+		}
+		// partitions may be already owned by other consumer,
+		// so offsets of lost partitions aren't committed
+		if !lost {
+			err := b.storeCompleted(stopped)
+			if err == nil {
+				err = b.commit()
+			}
+			if err != nil {
+				b.log.Error("committing offsets before revoke failed", "err", err.Error())
+			}
+		}
+		if b.listener != nil {
+			b.listener.OnRevoked(e.Partitions)
+		}
+
+		var err error
+		if protocol == cooperativeProtocol {
+			err = c.IncrementalUnassign(e.Partitions)
+		} else {
+			err = c.Unassign()
+		}
+		if err != nil {
+			b.log.Error("unassigning partitions failed", "partitions", e.Partitions, "err", err.Error())
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+package broker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/producer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/metrics"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// testConfig is loaded with bootstrap servers of mock cluster, offsets are committed
+// on revoke or close only, unless commitBatchSize or commitInterval is overridden
+const testConfig = `
+kafka:
+  kafkaUrl: %q
+  schemaRegistryURL: "mock://%s"
+  topic: %q
+  groupId: %q
+  assignmentStrategy: %q
+  commitBatchSize: 1000
+  commitInterval: "1h"
+`
+
+// newTestConfig returns config of consumer group named after the test
+func newTestConfig(t *testing.T, cluster *kafka.MockCluster, assignmentStrategy string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := fmt.Sprintf(
+		testConfig, cluster.BootstrapServers(), t.Name(), testTopic, t.Name(), assignmentStrategy,
+	)
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadByPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// produce sends n users to the test topic and returns
+// the number of messages delivered to each partition
+func produce(t *testing.T, cfg *config.Config, partitions, n int) []kafka.Offset {
+	t.Helper()
+	prod, err := producer.New[dto.User](cfg, testLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer prod.Close()
+	delivered := make([]kafka.Offset, partitions)
+	for i := range n {
+		name := fmt.Sprintf("user-%d", i)
+		tp, err := prod.SendSync(context.Background(), dto.User{Name: name}, testTopic, []byte(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		delivered[tp.Partition]++
+	}
+	return delivered
+}
+
+// listener records partition assignment changes
+type listener struct {
+	assigned [][]kafka.TopicPartition
+	revoked  [][]kafka.TopicPartition
+}
+
+func (l *listener) OnAssigned(partitions []kafka.TopicPartition) {
+	l.assigned = append(l.assigned, partitions)
+}
+
+func (l *listener) OnRevoked(partitions []kafka.TopicPartition) {
+	l.revoked = append(l.revoked, partitions)
+}
+
+// newTestConsumer returns consumer of the test topic with recording listener,
+// handled records are counted by handled
+func newTestConsumer(t *testing.T, cfg *config.Config, handled *int) (*Broker[dto.User, *dto.User], *listener) {
+	t.Helper()
+	handler := HandlerFunc[dto.User](func(context.Context, Record[dto.User]) error {
+		*handled++
+		return nil
+	})
+	b, err := New[dto.User](cfg, testLog, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &listener{}
+	b.SetRebalanceListener(l)
+	return b, l
+}
+
+// consumeUntil polls consumers in turn until done returns true
+func consumeUntil(t *testing.T, done func() bool, consumers ...*Broker[dto.User, *dto.User]) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for consumers")
+		}
+		for _, b := range consumers {
+			err := b.Consume(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// assignment returns partitions currently assigned to consumer
+func assignment(t *testing.T, b *Broker[dto.User, *dto.User]) []int32 {
+	t.Helper()
+	tps, err := b.consumer.Assignment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return partitionsOf(tps)
+}
+
+func partitionsOf(tps []kafka.TopicPartition) []int32 {
+	partitions := make([]int32, 0, len(tps))
+	for _, tp := range tps {
+		partitions = append(partitions, tp.Partition)
+	}
+	slices.Sort(partitions)
+	return partitions
+}
+
+// committed returns committed offsets of partitions of the test topic
+func committed(t *testing.T, b *Broker[dto.User, *dto.User], partitions int) []kafka.Offset {
+	t.Helper()
+	topic := testTopic
+	tps := make([]kafka.TopicPartition, 0, partitions)
+	for p := range partitions {
+		tps = append(tps, kafka.TopicPartition{Topic: &topic, Partition: int32(p)})
+	}
+	tps, err := b.consumer.Committed(tps, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets := make([]kafka.Offset, 0, len(tps))
+	for _, tp := range tps {
+		offsets = append(offsets, tp.Offset)
+	}
+	return offsets
+}
+
+func closeConsumer(t *testing.T, b *Broker[dto.User, *dto.User]) {
+	t.Helper()
+	err := b.Close()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRebalanceAssignRevoke(t *testing.T) {
+	cluster := newTestCluster(t, 2)
+	cfg := newTestConfig(t, cluster, "range")
+	var handled int
+	first, firstListener := newTestConsumer(t, cfg, &handled)
+	defer closeConsumer(t, first)
+
+	consumeUntil(t, func() bool { return len(firstListener.assigned) == 1 }, first)
+	if got := partitionsOf(firstListener.assigned[0]); !slices.Equal(got, []int32{0, 1}) {
+		t.Fatalf("first consumer is assigned %v, want [0 1]", got)
+	}
+
+	// lag of revoked partitions isn't exported anymore, but lag of other group
+	// consuming the same partitions is kept, statistics are off in tests
+	// so lag reported before revoke is set manually
+	metrics.ConsumerLag.WithLabelValues(first.groupID, testTopic, "0").Set(5)
+	metrics.ConsumerLag.WithLabelValues(first.groupID, testTopic, "1").Set(5)
+	other := metrics.ConsumerLag.WithLabelValues("other", testTopic, "0")
+	other.Set(7)
+	defer metrics.ConsumerLag.Reset()
+	second, secondListener := newTestConsumer(t, cfg, &handled)
+	defer closeConsumer(t, second)
+	consumeUntil(t, func() bool {
+		return len(firstListener.assigned) == 2 && len(secondListener.assigned) == 1
+	}, first, second)
+	if n := testutil.CollectAndCount(metrics.ConsumerLag); n != 1 {
+		t.Errorf("%d lag series are exported, want lag of other group only", n)
+	}
+	if got := testutil.ToFloat64(other); got != 7 {
+		t.Errorf("lag of other group is %v, want 7", got)
+	}
+
+	// eager assignor revokes all partitions before they are assigned again
+	if len(firstListener.revoked) != 1 {
+		t.Fatalf("first consumer got %d revokes, want 1", len(firstListener.revoked))
+	}
+	if got := partitionsOf(firstListener.revoked[0]); !slices.Equal(got, []int32{0, 1}) {
+		t.Errorf("first consumer is revoked %v, want [0 1]", got)
+	}
+	firstAssignment, secondAssignment := assignment(t, first), assignment(t, second)
+	if len(firstAssignment) != 1 || len(secondAssignment) != 1 || firstAssignment[0] == secondAssignment[0] {
+		t.Errorf("partitions are assigned %v and %v, want one to each consumer", firstAssignment, secondAssignment)
+	}
+}
+
+func TestRebalanceCooperative(t *testing.T) {
+	cluster := newTestCluster(t, 2)
+	cfg := newTestConfig(t, cluster, "cooperative-sticky")
+	var handled int
+	first, firstListener := newTestConsumer(t, cfg, &handled)
+	defer closeConsumer(t, first)
+
+	consumeUntil(t, func() bool { return len(firstListener.assigned) == 1 }, first)
+	if got := partitionsOf(firstListener.assigned[0]); !slices.Equal(got, []int32{0, 1}) {
+		t.Fatalf("first consumer is assigned %v, want [0 1]", got)
+	}
+
+	second, secondListener := newTestConsumer(t, cfg, &handled)
+	defer closeConsumer(t, second)
+	// the second consumer joins with empty assignment and gets the partition
+	// revoked from the first one by the following rebalance
+	consumeUntil(t, func() bool {
+		return len(firstListener.revoked) == 1 && len(assignment(t, second)) == 1
+	}, first, second)
+
+	// only the partition moved to the second consumer is revoked,
+	// the other one is kept without being assigned again
+	if len(firstListener.revoked[0]) != 1 {
+		t.Fatalf("first consumer is revoked %v, want one partition", firstListener.revoked[0])
+	}
+	for _, added := range firstListener.assigned[1:] {
+		if len(added) > 0 {
+			t.Errorf("first consumer is assigned again %v", added)
+		}
+	}
+	moved := firstListener.revoked[0][0].Partition
+	if got := partitionsOf(slices.Concat(secondListener.assigned...)); !slices.Equal(got, []int32{moved}) {
+		t.Errorf("second consumer is assigned %v, want [%d]", got, moved)
+	}
+	if got := assignment(t, first); len(got) != 1 || got[0] == moved {
+		t.Errorf("first consumer keeps %v, partition %d is moved", got, moved)
+	}
+}
+
+func TestRebalanceCommitsBeforeRevoke(t *testing.T) {
+	// mock cluster sometimes fails SyncGroup of the joining member, so the group
+	// rebalances again and rejects the commit, the scenario is repeated then
+	const attempts = 3
+	for attempt := range attempts {
+		if commitsBeforeRevoke(t) {
+			return
+		}
+		t.Logf("attempt %d: commit before revoke is rejected by another rebalance", attempt+1)
+	}
+	t.Skipf("mock cluster rejected commit before revoke %d times", attempts)
+}
+
+// commitsBeforeRevoke checks that offsets are committed when partition is revoked,
+// it returns false when mock cluster rejects the commit as rebalance is in progress
+func commitsBeforeRevoke(t *testing.T) (committedOnRevoke bool) {
+	t.Helper()
+	const partitions, messages = 2, 10
+	cluster := newTestCluster(t, partitions)
+	// mock cluster rejects commits while eager rebalance is in progress,
+	// with cooperative assignor partitions are revoked after it's completed
+	cfg := newTestConfig(t, cluster, "cooperative-sticky")
+	delivered := produce(t, cfg, partitions, messages)
+
+	var handled int
+	first, firstListener := newTestConsumer(t, cfg, &handled)
+	var logs bytes.Buffer
+	first.log = slog.New(slog.NewTextHandler(&logs, nil))
+	defer closeCommitted(t, first, &committedOnRevoke)
+
+	consumeUntil(t, func() bool { return handled == messages }, first)
+	// offsets are stored, but commit isn't due yet
+	for p, offset := range committed(t, first, partitions) {
+		if offset != kafka.OffsetInvalid {
+			t.Fatalf("partition %d is committed at %s before revoke", p, offset)
+		}
+	}
+
+	second, _ := newTestConsumer(t, cfg, &handled)
+	defer closeCommitted(t, second, &committedOnRevoke)
+	consumeUntil(t, func() bool { return len(firstListener.revoked) == 1 }, first, second)
+	if strings.Contains(logs.String(), kafka.ErrRebalanceInProgress.String()) {
+		return false
+	}
+	// offsets of all handled messages are committed, not only of the revoked partition
+	for p, offset := range committed(t, first, partitions) {
+		// empty partition has nothing to commit
+		want := delivered[p]
+		if want == 0 {
+			want = kafka.OffsetInvalid
+		}
+		if offset != want {
+			t.Errorf("partition %d is committed at %s, want %s", p, offset, want)
+		}
+	}
+	if handled != messages {
+		t.Errorf("%d messages are handled, want %d", handled, messages)
+	}
+	return true
+}
+
+// closeCommitted closes consumer, which can't commit on close either
+// while the group is rebalancing, so the error is ignored unless committed
+func closeCommitted(t *testing.T, b *Broker[dto.User, *dto.User], committed *bool) {
+	t.Helper()
+	err := b.Close()
+	if *committed && err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRebalanceLostAssignment(t *testing.T) {
+	const partitions, messages = 1, 3
+	cluster := newTestCluster(t, partitions)
+	cfg := newTestConfig(t, cluster, "range")
+	produce(t, cfg, partitions, messages)
+	// max.poll.interval.ms can't be less than session timeout
+	cfg.Kafka.SessionTimeout = 3 * time.Second
+	cfg.Consumer = config.Properties{"max.poll.interval.ms": "3000"}
+
+	var handled int
+	b, l := newTestConsumer(t, cfg, &handled)
+	defer closeConsumer(t, b)
+	consumeUntil(t, func() bool { return handled == messages }, b)
+
+	lost := metrics.Rebalances.WithLabelValues(b.groupID, "lost")
+	before := testutil.ToFloat64(lost)
+	// consumer leaves the group once it doesn't poll longer than max.poll.interval.ms
+	time.Sleep(4 * time.Second)
+	consumeUntil(t, func() bool { return len(l.revoked) == 1 }, b)
+
+	if got := testutil.ToFloat64(lost); got != before+1 {
+		t.Errorf("lost rebalances grew by %v, want 1", got-before)
+	}
+	// partitions may be already owned by other consumer, so nothing is committed
+	if got := committed(t, b, partitions); got[0] != kafka.OffsetInvalid {
+		t.Errorf("lost partition is committed at %s", got[0])
+	}
+}
+
+func TestRebalanceDropsPendingBatch(t *testing.T) {
+	topic := testTopic
+	message := func(p int32, offset kafka.Offset) *kafka.Message {
+		return &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: p, Offset: offset}}
+	}
+	b := &Broker[dto.User, *dto.User]{log: testLog}
+	b.pending = []*kafka.Message{message(0, 1), message(1, 1), message(0, 2), message(2, 1)}
+
+	b.dropPending([]kafka.TopicPartition{{Topic: &topic, Partition: 0}, {Topic: &topic, Partition: 2}})
+	if len(b.pending) != 1 || b.pending[0].TopicPartition.Partition != 1 {
+		t.Errorf("pending batch is %v, want message of partition 1 only", b.pending)
+	}
+}
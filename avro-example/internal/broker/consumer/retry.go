@@ -0,0 +1,172 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+var (
+	// ErrRetryable marks handler errors which are worth retrying later,
+	// wrap handler errors with Retryable.
+	ErrRetryable = errors.New("retryable")
+	ErrRetry     = errors.New("sending message to retry topic failed")
+)
+
+// headers added to messages sent to retry topics
+const (
+	HeaderRetryAttempt     = "retry.attempt"
+	HeaderRetryAt          = "retry.at"
+	HeaderRetrySourceTopic = "retry.source.topic"
+	HeaderRetryError       = "retry.error"
+)
+
+// Retryable marks err as transient, so the message is sent to the next retry topic
+func Retryable(err error) error {
+	return fmt.Errorf("%w: %w", ErrRetryable, err)
+}
+
+// RetryStages returns consumers of retry topics <topic>.retry.1, <topic>.retry.2 ...
+// Each of them has to be polled by its own goroutine, so waiting
+// for retry time doesn't block the source topic.
+func (b *Broker[T, PT]) RetryStages() []*Broker[T, PT] {
+	return b.stages
+}
+
+// reroute sends failed message to the next retry topic or dead letter topic.
+// It returns cause if the message has to be consumed again.
+// Messages failed on shutdown or revoke (ctx is done) are consumed again
+// as well, as waiting for retry time or handling was just interrupted.
+func (b *Broker[T, PT]) reroute(ctx context.Context, e *kafka.Message, cause error) error {
+	if ctx.Err() != nil {
+		return cause
+	}
+	if b.retry.Enabled && errors.Is(cause, ErrRetryable) && b.stage < b.retry.Attempts {
+		return b.retryLater(ctx, e, cause)
+	}
+	if b.dlqEnabled {
+		return b.deadLetter(ctx, e, cause)
+	}
+	return cause
+}
+
+// retryLater re-produces original bytes, key and headers of e to
+// the next retry topic with the time the message should be handled at
+func (b *Broker[T, PT]) retryLater(ctx context.Context, e *kafka.Message, cause error) error {
+	source := sourceTopic(e)
+	attempt := b.stage + 1
+	topic := retryTopic(source, attempt)
+	retryAt := time.Now().Add(b.backoff(attempt))
+
+	headers := make([]kafka.Header, 0, len(e.Headers)+4)
+	for _, h := range e.Headers {
+		// previous attempt headers are replaced
+		if strings.HasPrefix(h.Key, "retry.") {
+			continue
+		}
+		headers = append(headers, h)
+	}
+	headers = append(headers,
+		kafka.Header{Key: HeaderRetryAttempt, Value: []byte(strconv.Itoa(attempt))},
+		kafka.Header{Key: HeaderRetryAt, Value: []byte(retryAt.Format(time.RFC3339Nano))},
+		kafka.Header{Key: HeaderRetrySourceTopic, Value: []byte(source)},
+		kafka.Header{Key: HeaderRetryError, Value: []byte(cause.Error())},
+	)
+
+	// once sending is started, it isn't interrupted by shutdown,
+	// otherwise the message may be both forwarded and consumed again
+	tp, err := b.forwarder.SendRaw(context.WithoutCancel(ctx), &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            e.Key,
+		Value:          e.Value,
+		Headers:        headers,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w: %w", ErrRetry, err, cause)
+	}
+	b.log.Warn(
+		"message sent to retry topic",
+		"source", e.TopicPartition, "retry", tp, "retryAt", retryAt, "err", cause.Error(),
+	)
+	return nil
+}
+
+// waitRetryAt blocks until the time stored in retry.at header of e
+func (b *Broker[T, PT]) waitRetryAt(ctx context.Context, e *kafka.Message) error {
+	value, ok := header(e, HeaderRetryAt)
+	if !ok {
+		return nil
+	}
+	retryAt, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return fmt.Errorf("wrong %s header: %w", HeaderRetryAt, err)
+	}
+	delay := time.Until(retryAt)
+	if delay <= 0 {
+		return nil
+	}
+	b.log.Debug("waiting for retry time", "partition", e.TopicPartition, "retryAt", retryAt)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff returns delay before retry attempt,
+// it grows exponentially and is limited by MaxBackoff
+func (b *Broker[T, PT]) backoff(attempt int) time.Duration {
+	delay := float64(b.retry.Backoff) * math.Pow(b.retry.Multiplier, float64(attempt-1))
+	if delay > float64(b.retry.MaxBackoff) {
+		return b.retry.MaxBackoff
+	}
+	return time.Duration(delay)
+}
+
+// retryTopic returns name of retry topic for the given attempt
+func retryTopic(topic string, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", topic, attempt)
+}
+
+// retrySubscription returns retry topics of the given attempt for
+// source subscription, regex subscriptions are turned to retry topics regex
+func retrySubscription(topics []string, attempt int) []string {
+	retryTopics := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		if strings.HasPrefix(topic, "^") {
+			pattern := strings.TrimSuffix(strings.TrimPrefix(topic, "^"), "$")
+			// librdkafka regex has no non-capturing groups
+			retryTopics = append(retryTopics, fmt.Sprintf(`^(%s)\.retry\.%d$`, pattern, attempt))
+			continue
+		}
+		retryTopics = append(retryTopics, retryTopic(topic, attempt))
+	}
+	return retryTopics
+}
+
+// sourceTopic returns topic the message was originally consumed from
+func sourceTopic(e *kafka.Message) string {
+	if source, ok := header(e, HeaderRetrySourceTopic); ok {
+		return source
+	}
+	return *e.TopicPartition.Topic
+}
+
+// header returns value of the last header with the given key
+func header(e *kafka.Message, key string) (string, bool) {
+	for i := len(e.Headers) - 1; i >= 0; i-- {
+		if e.Headers[i].Key == key {
+			return string(e.Headers[i].Value), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,203 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/clientconfig"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/producer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+var ErrTransform = errors.New("transforming messages failed")
+
+// TransformFunc turns consumed record into records produced to the output topic,
+// it may return no records to filter the consumed one out
+type TransformFunc[In, Out any] func(ctx context.Context, msg Record[In]) ([]Out, error)
+
+type transactionalSender[T any] interface {
+	SendAsync(ctx context.Context, msg T, topic string, key []byte) (<-chan producer.Delivery, error)
+	BeginTransaction() error
+	SendOffsetsToTransaction(ctx context.Context, offsets []kafka.TopicPartition, metadata *kafka.ConsumerGroupMetadata) error
+	CommitTransaction(ctx context.Context) error
+	AbortTransaction(ctx context.Context) error
+	Close() error
+	Live() error
+	Ready(ctx context.Context) error
+}
+
+// Transformer consumes records of type In and produces transformed records of type Out.
+// Produced records and consumed offsets of every batch are committed in one kafka
+// transaction, so each consumed record is transformed exactly once.
+// Consumer reads only committed messages (isolation.level=read_committed).
+type Transformer[In any, PIn dto.AvroRecord[In], Out any] struct {
+	consumer  *Broker[In, PIn]
+	producer  transactionalSender[Out]
+	transform TransformFunc[In, Out]
+	topic     string
+	timeout   time.Duration
+	log       *slog.Logger
+}
+
+// NewTransformer returns transformer of records consumed from kafka.topic (topics, topicPattern)
+// to records produced to kafka.transaction.outputTopic. Records are consumed in batches
+// according to batch settings, retry topics aren't supported.
+func NewTransformer[In any, Out any, PIn dto.AvroRecord[In], POut dto.AvroRecord[Out]](
+	ctx context.Context,
+	cfg *config.Config,
+	log *slog.Logger,
+	transform TransformFunc[In, Out],
+) (*Transformer[In, PIn, Out], error) {
+	if cfg.Kafka.Transaction.ID == "" || cfg.Kafka.Transaction.OutputTopic == "" {
+		return nil, fmt.Errorf("%w: transformer requires transaction.id and transaction.outputTopic", config.ErrInvalidConfig)
+	}
+	prod, err := producer.NewTransactional[Out, POut](ctx, cfg, log, cfg.Kafka.Transaction.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var forwarder rawSendCloser
+	if cfg.Kafka.DLQ.Enabled {
+		forwarder, err = producer.New[In, PIn](cfg, log)
+		if err != nil {
+			return nil, errors.Join(err, prod.Close())
+		}
+	}
+	configMap, err := clientconfig.Consumer(cfg, cfg.Kafka.GroupID)
+	if err != nil {
+		return nil, errors.Join(err, prod.Close())
+	}
+	// messages of aborted transactions are skipped
+	err = configMap.SetKey("isolation.level", "read_committed")
+	if err != nil {
+		return nil, errors.Join(err, prod.Close())
+	}
+	cons, err := newBroker[In, PIn](cfg, log, configMap, nil, cfg.Kafka.Subscription(), 0, forwarder)
+	if err != nil {
+		return nil, errors.Join(err, prod.Close())
+	}
+	cons.batchSize = cfg.Kafka.Batch.Size
+	cons.batchTimeout = cfg.Kafka.Batch.Timeout
+	cons.retry.Enabled = false
+
+	return &Transformer[In, PIn, Out]{
+		consumer:  cons,
+		producer:  prod,
+		transform: transform,
+		topic:     cfg.Kafka.Transaction.OutputTopic,
+		timeout:   cfg.Kafka.Transaction.Timeout,
+		log:       log.With("outputTopic", cfg.Kafka.Transaction.OutputTopic),
+	}, nil
+}
+
+// Close closes consumer and then producer.
+// WARNING: Transform method need to be finished before.
+func (t *Transformer[In, PIn, Out]) Close() error {
+	return errors.Join(t.consumer.Close(), t.producer.Close())
+}
+
+// Transform collects batch of records, transforms them and commits produced records
+// together with consumed offsets. On failure transaction is aborted and
+// partitions are rewound, so the batch is consumed again by the next call.
+// Records which can't be deserialized or transformed are sent to the dead letter
+// topic (if enabled) instead, it isn't a part of transaction.
+func (t *Transformer[In, PIn, Out]) Transform(ctx context.Context) error {
+	messages := t.consumer.collect(ctx)
+	if len(messages) == 0 {
+		return nil
+	}
+	err := t.transaction(ctx, messages)
+	if err != nil {
+		seekErr := t.consumer.rewind(messages)
+		if seekErr != nil {
+			return fmt.Errorf("%w, seek failed: %w", err, seekErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// transaction produces transformed messages and commits them with consumed offsets.
+// Once started, transaction isn't interrupted by ctx cancellation.
+func (t *Transformer[In, PIn, Out]) transaction(ctx context.Context, messages []*kafka.Message) (err error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), t.timeout)
+	defer cancel()
+
+	err = t.producer.BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, t.producer.AbortTransaction(ctx))
+			t.log.Warn("transaction aborted", "err", err.Error())
+		}
+	}()
+
+	for _, e := range messages {
+		err = t.produce(ctx, e)
+		if err != nil {
+			return err
+		}
+	}
+	metadata, err := t.consumer.consumer.GetConsumerGroupMetadata()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCommit, err)
+	}
+	err = t.producer.SendOffsetsToTransaction(ctx, nextOffsets(messages), metadata)
+	if err != nil {
+		return err
+	}
+	err = t.producer.CommitTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	t.log.Debug("transaction committed", "messages", len(messages))
+	return nil
+}
+
+// produce transforms message and sends the result to the output topic,
+// failed message is sent to the dead letter topic if it is enabled.
+// Produced records keep the key of the consumed one.
+func (t *Transformer[In, PIn, Out]) produce(ctx context.Context, e *kafka.Message) error {
+	ctx = extract(ctx, e)
+	record, err := t.consumer.deserialize(e)
+	if err != nil {
+		return t.consumer.reroute(ctx, e, err)
+	}
+	outputs, err := t.transform(ctx, record)
+	if err != nil {
+		return t.consumer.reroute(ctx, e, fmt.Errorf("%w: %w", ErrTransform, err))
+	}
+	for _, output := range outputs {
+		// delivery is confirmed by transaction commit
+		_, err = t.producer.SendAsync(ctx, output, t.topic, e.Key)
+		if errors.Is(err, producer.ErrValidation) {
+			// invalid output won't become valid on the next attempt
+			return t.consumer.reroute(ctx, e, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Live reports whether poll loop of the consumer is alive
+func (t *Transformer[In, PIn, Out]) Live() error {
+	return errors.Join(t.consumer.Live(), t.producer.Live())
+}
+
+// Ready reports whether kafka and schema registry are reachable and partitions are assigned
+func (t *Transformer[In, PIn, Out]) Ready(ctx context.Context) error {
+	err := t.producer.Ready(ctx)
+	if err != nil {
+		return err
+	}
+	return t.consumer.Ready(ctx)
+}
@@ -0,0 +1,257 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/metrics"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// WorkerRetryDelay is how long worker waits before handling failed message again
+var WorkerRetryDelay = time.Second
+
+// partitionWorkers handles messages of one partition. Messages are spread over
+// workers by key hash, so messages with the same key are handled in order.
+type partitionWorkers struct {
+	tp     kafka.TopicPartition
+	queues []chan *kafka.Message
+	wg     sync.WaitGroup
+	// ctx is cancelled when partition is revoked,
+	// it stops waiting before handling failed message again
+	ctx    context.Context
+	cancel context.CancelFunc
+	paused bool
+
+	mu sync.Mutex
+	// offsets of dispatched messages which aren't handled yet, ascending
+	inFlight []kafka.Offset
+	// handled offsets which can't be committed, as a lower one is in flight
+	done map[kafka.Offset]struct{}
+	// the next offset to commit and number of messages handled before it
+	committable kafka.Offset
+	completed   int
+}
+
+// consumeConcurrently polls one event and dispatches received message to
+// the worker of its partition. Offsets are stored up to the lowest
+// message which isn't handled yet, so no message is lost on restart.
+func (b *Broker[T, PT]) consumeConcurrently(ctx context.Context) error {
+	b.lastPoll.Store(time.Now().UnixNano())
+	ev := b.consumer.Poll(b.pollTimeout)
+	switch e := ev.(type) {
+	case nil:
+	case *kafka.Message:
+		metrics.MessagesConsumed.WithLabelValues(*e.TopicPartition.Topic).Inc()
+		b.dispatch(ctx, e)
+	default:
+		b.event(ev)
+	}
+	b.throttle()
+	err := b.storeCompleted(b.workers)
+	if err != nil {
+		return err
+	}
+	return b.commitIfDue()
+}
+
+// dispatch passes message to the worker chosen by message key
+func (b *Broker[T, PT]) dispatch(ctx context.Context, e *kafka.Message) {
+	p := partition{topic: *e.TopicPartition.Topic, partition: e.TopicPartition.Partition}
+	w, ok := b.workers[p]
+	if !ok {
+		w = b.startWorkers(e.TopicPartition)
+		b.workers[p] = w
+	}
+	w.mu.Lock()
+	w.inFlight = append(w.inFlight, e.TopicPartition.Offset)
+	w.mu.Unlock()
+
+	queue := w.queues[0]
+	if len(w.queues) > 1 {
+		h := fnv.New32a()
+		h.Write(e.Key)
+		queue = w.queues[h.Sum32()%uint32(len(w.queues))]
+	}
+	select {
+	case queue <- e:
+	case <-ctx.Done():
+		// the message isn't handled, so its offset is never stored
+	}
+}
+
+// startWorkers starts workers of the partition
+func (b *Broker[T, PT]) startWorkers(tp kafka.TopicPartition) *partitionWorkers {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &partitionWorkers{
+		tp:          kafka.TopicPartition{Topic: tp.Topic, Partition: tp.Partition},
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(map[kafka.Offset]struct{}),
+		committable: kafka.OffsetInvalid,
+	}
+	for range b.keyWorkers {
+		queue := make(chan *kafka.Message, b.queueSize)
+		w.queues = append(w.queues, queue)
+		w.wg.Add(1)
+		go b.work(w, queue)
+	}
+	b.log.Debug("partition workers started", "partition", w.tp)
+	return w
+}
+
+// work handles messages of the queue in order. Once a message can't be
+// handled before partition is revoked, the next ones are skipped,
+// so they are handled in order by the next partition owner.
+func (b *Broker[T, PT]) work(w *partitionWorkers, queue <-chan *kafka.Message) {
+	defer w.wg.Done()
+	failed := false
+	for e := range queue {
+		if failed {
+			continue
+		}
+		if !b.handleUntilDone(w, e) {
+			failed = true
+			continue
+		}
+		w.complete(e.TopicPartition.Offset)
+	}
+}
+
+// handleUntilDone processes message until it is handled or rerouted,
+// it gives up when partition is revoked
+func (b *Broker[T, PT]) handleUntilDone(w *partitionWorkers, e *kafka.Message) bool {
+	for {
+		err := b.process(w.ctx, e)
+		if err != nil && w.ctx.Err() != nil {
+			// waiting for retry time was interrupted
+			return false
+		}
+		if err != nil {
+			err = b.reroute(w.ctx, e, err)
+		}
+		if err == nil {
+			return true
+		}
+		b.log.Error("message processing failed", "partition", e.TopicPartition, "err", err.Error())
+		timer := time.NewTimer(WorkerRetryDelay)
+		select {
+		case <-w.ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// complete marks offset as handled and advances committable offset
+// over contiguous handled messages
+func (w *partitionWorkers) complete(offset kafka.Offset) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done[offset] = struct{}{}
+	for len(w.inFlight) > 0 {
+		lowest := w.inFlight[0]
+		if _, ok := w.done[lowest]; !ok {
+			break
+		}
+		delete(w.done, lowest)
+		w.inFlight = w.inFlight[1:]
+		w.committable = lowest + 1
+		w.completed++
+	}
+}
+
+// takeCommittable returns the next offset to commit and number of messages
+// handled since the previous call
+func (w *partitionWorkers) takeCommittable() (kafka.Offset, int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	completed := w.completed
+	w.completed = 0
+	return w.committable, completed
+}
+
+// pending returns number of dispatched messages which aren't committable yet
+func (w *partitionWorkers) pending() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.inFlight)
+}
+
+// stop waits for workers to handle queued messages,
+// failed messages aren't handled again
+func (w *partitionWorkers) stop() {
+	w.cancel()
+	for _, queue := range w.queues {
+		close(queue)
+	}
+	w.wg.Wait()
+}
+
+// storeCompleted stores committable offsets of workers
+func (b *Broker[T, PT]) storeCompleted(workers map[partition]*partitionWorkers) error {
+	var offsets []kafka.TopicPartition
+	for _, w := range workers {
+		offset, completed := w.takeCommittable()
+		if completed == 0 {
+			continue
+		}
+		tp := w.tp
+		tp.Offset = offset
+		offsets = append(offsets, tp)
+		b.uncommitted += completed
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+	_, err := b.consumer.StoreOffsets(offsets)
+	if err != nil {
+		metrics.CommitFailures.Inc()
+		return fmt.Errorf("%w: %w", ErrCommit, err)
+	}
+	return nil
+}
+
+// throttle pauses partitions which have queueSize messages in flight
+// and resumes them once half of the messages are handled
+func (b *Broker[T, PT]) throttle() {
+	for _, w := range b.workers {
+		pending := w.pending()
+		var err error
+		switch {
+		case !w.paused && pending >= b.queueSize:
+			err = b.consumer.Pause([]kafka.TopicPartition{w.tp})
+			w.paused = err == nil
+		case w.paused && pending <= b.queueSize/2:
+			err = b.consumer.Resume([]kafka.TopicPartition{w.tp})
+			w.paused = err != nil
+		}
+		if err != nil {
+			b.log.Error("pausing or resuming partition failed", "partition", w.tp, "err", err.Error())
+		}
+	}
+}
+
+// stopWorkers drains workers of the partitions (all of them when partitions is nil)
+// and returns them, so their offsets can be stored before partitions are revoked
+func (b *Broker[T, PT]) stopWorkers(partitions []kafka.TopicPartition) map[partition]*partitionWorkers {
+	stopped := make(map[partition]*partitionWorkers)
+	for p, w := range b.workers {
+		if partitions == nil || slices.ContainsFunc(partitions, func(tp kafka.TopicPartition) bool {
+			return *tp.Topic == p.topic && tp.Partition == p.partition
+		}) {
+			stopped[p] = w
+		}
+	}
+	for p, w := range stopped {
+		w.stop()
+		delete(b.workers, p)
+		b.log.Debug("partition workers stopped", "partition", w.tp)
+	}
+	return stopped
+}
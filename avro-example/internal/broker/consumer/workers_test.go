@@ -0,0 +1,297 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"testing"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/avro"
+	"go.opentelemetry.io/otel"
+)
+
+func TestPartitionWorkersCommittable(t *testing.T) {
+	tests := []struct {
+		name string
+		// completed offsets in order of completion, 10..14 are in flight
+		completed       []kafka.Offset
+		wantCommittable kafka.Offset
+		wantCompleted   int
+	}{
+		{"nothing completed", nil, kafka.OffsetInvalid, 0},
+		{"in order", []kafka.Offset{10, 11}, 12, 2},
+		{"gap", []kafka.Offset{11, 12}, kafka.OffsetInvalid, 0},
+		{"gap filled", []kafka.Offset{11, 12, 10}, 13, 3},
+		{"reverse order", []kafka.Offset{14, 13, 12, 11, 10}, 15, 5},
+		{"gap in the middle", []kafka.Offset{10, 12, 13}, 11, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &partitionWorkers{
+				inFlight:    []kafka.Offset{10, 11, 12, 13, 14},
+				done:        make(map[kafka.Offset]struct{}),
+				committable: kafka.OffsetInvalid,
+			}
+			for _, offset := range tt.completed {
+				w.complete(offset)
+			}
+			committable, completed := w.takeCommittable()
+			if committable != tt.wantCommittable || completed != tt.wantCompleted {
+				t.Errorf("got %s after %d messages, want %s after %d",
+					committable, completed, tt.wantCommittable, tt.wantCompleted)
+			}
+			if want := 5 - tt.wantCompleted; w.pending() != want {
+				t.Errorf("%d messages are pending, want %d", w.pending(), want)
+			}
+			// completed messages are reported once
+			committable, completed = w.takeCommittable()
+			if committable != tt.wantCommittable || completed != 0 {
+				t.Errorf("second call got %s after %d messages", committable, completed)
+			}
+		})
+	}
+}
+
+// workersTest dispatches serialized users to partition workers of broker
+type workersTest struct {
+	b          *Broker[dto.User, *dto.User]
+	serializer serde.Serializer
+	topic      string
+}
+
+// newWorkersTest returns broker in concurrent mode with consumer manually
+// assigned partition 0 of the test topic, messages are handled by handler
+func newWorkersTest(t *testing.T, keyWorkers, queueSize int, handler HandlerFunc[dto.User]) *workersTest {
+	t.Helper()
+	cluster := newTestCluster(t, 1)
+	c, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": cluster.BootstrapServers(),
+		"group.id":          t.Name(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	topic := testTopic
+	err = c.Assign([]kafka.TopicPartition{{Topic: &topic, Partition: 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := schemaregistry.NewClient(schemaregistry.NewConfig("mock://" + t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deser, err := avro.NewSpecificDeserializer(client, serde.ValueSerde, avro.NewDeserializerConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ser, err := avro.NewSpecificSerializer(client, serde.ValueSerde, avro.NewSerializerConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &workersTest{
+		b: &Broker[dto.User, *dto.User]{
+			consumer:     c,
+			deserializer: deser,
+			handler:      handler,
+			log:          testLog,
+			tracer:       otel.Tracer(tracerName),
+			workers:      make(map[partition]*partitionWorkers),
+			keyWorkers:   keyWorkers,
+			queueSize:    queueSize,
+		},
+		serializer: ser,
+		topic:      topic,
+	}
+}
+
+// dispatch passes user with the given name as key to workers
+func (wt *workersTest) dispatch(t *testing.T, offset kafka.Offset, name string) {
+	t.Helper()
+	value, err := wt.serializer.Serialize(wt.topic, &dto.User{Name: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.b.dispatch(context.Background(), &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &wt.topic, Partition: 0, Offset: offset},
+		Key:            []byte(name),
+		Value:          value,
+	})
+}
+
+// workers returns workers of partition 0 of the test topic
+func (wt *workersTest) workers() *partitionWorkers {
+	return wt.b.workers[partition{topic: wt.topic, partition: 0}]
+}
+
+// waitFor fails the test when cond isn't true in time
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for workers")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// keysOfDifferentWorkers returns keys handled by different workers out of n
+func keysOfDifferentWorkers(n int) (string, string) {
+	queue := func(key string) uint32 {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		return h.Sum32() % uint32(n)
+	}
+	keys := []string{"alice", "bob", "carol", "dave", "eve", "frank"}
+	for _, key := range keys[1:] {
+		if queue(key) != queue(keys[0]) {
+			return keys[0], key
+		}
+	}
+	panic("all keys are handled by the same worker")
+}
+
+func TestWorkersOutOfOrderCompletion(t *testing.T) {
+	slow, fast := keysOfDifferentWorkers(2)
+	release := make(chan struct{})
+	handled := make(chan string, 10)
+	wt := newWorkersTest(t, 2, 10, func(_ context.Context, msg Record[dto.User]) error {
+		if msg.Value.Name == slow {
+			<-release
+		}
+		handled <- msg.Value.Name
+		return nil
+	})
+	defer wt.b.stopWorkers(nil)
+
+	wt.dispatch(t, 0, slow)
+	wt.dispatch(t, 1, fast)
+	wt.dispatch(t, 2, fast)
+	for range 2 {
+		if name := <-handled; name != fast {
+			t.Fatalf("%s is handled before %s is released", name, slow)
+		}
+	}
+	// later messages are handled, but the first one is still in flight
+	if committable, completed := wt.workers().takeCommittable(); completed != 0 {
+		t.Fatalf("offset %s is committable before offset 0 is handled", committable)
+	}
+	if pending := wt.workers().pending(); pending != 3 {
+		t.Errorf("%d messages are pending, want 3", pending)
+	}
+
+	close(release)
+	<-handled
+	waitFor(t, func() bool { return wt.workers().pending() == 0 })
+	if committable, completed := wt.workers().takeCommittable(); committable != 3 || completed != 3 {
+		t.Errorf("got %s after %d messages, want 3 after 3", committable, completed)
+	}
+}
+
+func TestWorkersThrottle(t *testing.T) {
+	const queueSize = 4
+	release := make(chan struct{})
+	wt := newWorkersTest(t, 1, queueSize, func(context.Context, Record[dto.User]) error {
+		<-release
+		return nil
+	})
+	defer func() {
+		close(release)
+		wt.b.stopWorkers(nil)
+	}()
+
+	for offset := range queueSize - 1 {
+		wt.dispatch(t, kafka.Offset(offset), "alice")
+	}
+	wt.b.throttle()
+	if wt.workers().paused {
+		t.Fatalf("partition is paused with %d messages in flight", queueSize-1)
+	}
+
+	wt.dispatch(t, queueSize-1, "alice")
+	wt.b.throttle()
+	if !wt.workers().paused {
+		t.Fatalf("partition isn't paused with %d messages in flight", queueSize)
+	}
+
+	// partition is resumed once half of messages are handled
+	release <- struct{}{}
+	waitFor(t, func() bool { return wt.workers().pending() == queueSize-1 })
+	wt.b.throttle()
+	if !wt.workers().paused {
+		t.Fatalf("partition is resumed with %d messages in flight", queueSize-1)
+	}
+	release <- struct{}{}
+	waitFor(t, func() bool { return wt.workers().pending() == queueSize/2 })
+	wt.b.throttle()
+	if wt.workers().paused {
+		t.Errorf("partition isn't resumed with %d messages in flight", queueSize/2)
+	}
+}
+
+func TestWorkersDrainOnRevoke(t *testing.T) {
+	var handled []string
+	wt := newWorkersTest(t, 1, 10, func(_ context.Context, msg Record[dto.User]) error {
+		time.Sleep(10 * time.Millisecond)
+		handled = append(handled, msg.Value.Name)
+		return nil
+	})
+	names := []string{"alice", "bob", "carol"}
+	for offset, name := range names {
+		wt.dispatch(t, kafka.Offset(offset), name)
+	}
+
+	tp := kafka.TopicPartition{Topic: &wt.topic, Partition: 0}
+	stopped := wt.b.stopWorkers([]kafka.TopicPartition{tp})
+	// queued messages are handled before partition is revoked
+	if len(handled) != len(names) {
+		t.Fatalf("handled %v before revoke, want %v", handled, names)
+	}
+	if len(wt.b.workers) != 0 {
+		t.Errorf("workers of revoked partition are kept")
+	}
+	w, ok := stopped[partition{topic: wt.topic, partition: 0}]
+	if !ok {
+		t.Fatal("workers of revoked partition aren't returned")
+	}
+	if committable, completed := w.takeCommittable(); committable != 3 || completed != 3 {
+		t.Errorf("got %s after %d messages, want 3 after 3", committable, completed)
+	}
+}
+
+func TestWorkersDrainOnRevokeSkipsFailed(t *testing.T) {
+	defer func(delay time.Duration) { WorkerRetryDelay = delay }(WorkerRetryDelay)
+	WorkerRetryDelay = time.Hour
+	failed := make(chan struct{}, 10)
+	var handled []kafka.Offset
+	wt := newWorkersTest(t, 1, 10, func(_ context.Context, msg Record[dto.User]) error {
+		if msg.Offset == 1 {
+			failed <- struct{}{}
+			return errors.New("handler failed")
+		}
+		handled = append(handled, msg.Offset)
+		return nil
+	})
+	for offset := range 3 {
+		wt.dispatch(t, kafka.Offset(offset), "alice")
+	}
+	<-failed
+
+	stopped := wt.b.stopWorkers(nil)
+	// message after the failed one isn't handled, so it's handled
+	// in order by the next owner of the partition
+	if len(handled) != 1 || handled[0] != 0 {
+		t.Errorf("handled offsets %v, want [0]", handled)
+	}
+	w := stopped[partition{topic: wt.topic, partition: 0}]
+	if committable, completed := w.takeCommittable(); committable != 1 || completed != 1 {
+		t.Errorf("got %s after %d messages, want 1 after 1", committable, completed)
+	}
+}
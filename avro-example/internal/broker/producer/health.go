@@ -0,0 +1,27 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/health"
+)
+
+// Live reports whether the producer is able to work at all,
+// it fails after fatal kafka error
+func (b *Broker[T, PT]) Live() error {
+	return b.Err()
+}
+
+// Ready reports whether kafka brokers and schema registry are reachable
+func (b *Broker[T, PT]) Ready(ctx context.Context) error {
+	_, err := b.producer.GetMetadata(nil, false, health.TimeoutMs(ctx))
+	if err != nil {
+		return fmt.Errorf("%w: %w", health.ErrKafkaUnreachable, err)
+	}
+	err = health.CheckSchemaRegistry(ctx, b.registry)
+	if err != nil {
+		return err
+	}
+	return b.Live()
+}
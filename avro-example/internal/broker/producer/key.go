@@ -0,0 +1,128 @@
+package producer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrKeyField = errors.New("wrong key field")
+	// ErrNoKeyFunc means custom key strategy is configured, but KeyFunc isn't set with SetKeyFunc
+	ErrNoKeyFunc = errors.New("key function isn't set")
+)
+
+// KeyFunc returns key of message, nil key means message has no key
+// and partition is chosen by partitioner for null keys
+type KeyFunc[T any] func(msg T) []byte
+
+// NewKeyFunc returns KeyFunc of the configured key strategy.
+// Custom strategy returns nil KeyFunc, Send fails until KeyFunc is set with SetKeyFunc.
+func NewKeyFunc[T any](cfg config.KeyConfig) (KeyFunc[T], error) {
+	switch cfg.Strategy {
+	case config.KeyStrategyField:
+		return FieldKey[T](cfg.Field)
+	case config.KeyStrategyUUID:
+		return UUIDKey[T], nil
+	case config.KeyStrategyRandom:
+		return RandomKey[T], nil
+	case config.KeyStrategyCustom:
+		return nil, nil
+	default:
+		return NullKey[T], nil
+	}
+}
+
+// NullKey sends messages without key, librdkafka spreads them over partitions
+func NullKey[T any](T) []byte {
+	return nil
+}
+
+// UUIDKey returns random UUID as message key
+func UUIDKey[T any](T) []byte {
+	return []byte(uuid.NewString())
+}
+
+// RandomKey returns 8 random bytes in hex as message key
+func RandomKey[T any](T) []byte {
+	key := make([]byte, 8)
+	// crypto/rand Read never returns an error
+	_, _ = rand.Read(key)
+	return []byte(hex.EncodeToString(key))
+}
+
+// FieldKey returns KeyFunc using value of record field as message key.
+// path is a dot separated list of struct fields, optionally prefixed
+// with the record type name, e.g. User.Name. Fields are matched by
+// Go name or json tag, key field must be a string, bool or number.
+func FieldKey[T any](path string) (KeyFunc[T], error) {
+	t := reflect.TypeFor[T]()
+	names := strings.Split(path, ".")
+	if len(names) > 1 && names[0] == t.Name() {
+		names = names[1:]
+	}
+	var index []int
+	for _, name := range names {
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%w: %s: %s is not a struct", ErrKeyField, path, t)
+		}
+		field, ok := fieldByName(t, name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s: %s has no field %s", ErrKeyField, path, t, name)
+		}
+		index = append(index, field.Index...)
+		t = field.Type
+	}
+	if !keyKind(t.Kind()) {
+		return nil, fmt.Errorf("%w: %s: %s can't be used as key", ErrKeyField, path, t)
+	}
+	return func(msg T) []byte {
+		v := reflect.ValueOf(msg).FieldByIndex(index)
+		return []byte(fmt.Sprint(v.Interface()))
+	}, nil
+}
+
+// keyKind reports whether field of kind k has a plain text representation usable as key
+func keyKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// fieldByName returns exported field of struct type t by Go name or json tag
+func fieldByName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() {
+			continue
+		}
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Name == name || tag == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// SetKeyFunc sets key strategy used by Send, e.g. for custom key strategy
+func (b *Broker[T, PT]) SetKeyFunc(key KeyFunc[T]) {
+	b.key = key
+}
+
+// Key returns message key of msg according to the key strategy,
+// it fails with ErrNoKeyFunc until KeyFunc of custom strategy is set
+func (b *Broker[T, PT]) Key(msg T) ([]byte, error) {
+	if b.key == nil {
+		return nil, ErrNoKeyFunc
+	}
+	return b.key(msg), nil
+}
@@ -0,0 +1,122 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+)
+
+type address struct {
+	City string `json:"city"`
+	Zip  int32
+}
+
+type order struct {
+	ID      int64 `json:"id"`
+	Paid    bool
+	Price   float64
+	Tags    []string
+	Address address `json:"address"`
+	Note    *string
+}
+
+func TestKeyStrategies(t *testing.T) {
+	user := dto.User{Name: "Alice", Favorite_number: 7}
+	tests := []struct {
+		name string
+		cfg  config.KeyConfig
+		want *regexp.Regexp
+	}{
+		{"field by go name", config.KeyConfig{Strategy: config.KeyStrategyField, Field: "Name"}, regexp.MustCompile(`^Alice$`)},
+		{"field by json tag", config.KeyConfig{Strategy: config.KeyStrategyField, Field: "favorite_number"}, regexp.MustCompile(`^7$`)},
+		{"field with type name", config.KeyConfig{Strategy: config.KeyStrategyField, Field: "User.name"}, regexp.MustCompile(`^Alice$`)},
+		{"uuid", config.KeyConfig{Strategy: config.KeyStrategyUUID}, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)},
+		{"random", config.KeyConfig{Strategy: config.KeyStrategyRandom}, regexp.MustCompile(`^[0-9a-f]{16}$`)},
+		{"null", config.KeyConfig{Strategy: config.KeyStrategyNull}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := NewKeyFunc[dto.User](tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := key(user)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("got key %q, want nil", got)
+				}
+				return
+			}
+			if !tt.want.Match(got) {
+				t.Errorf("got key %q, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRandomKeysDiffer(t *testing.T) {
+	for _, key := range []KeyFunc[dto.User]{UUIDKey[dto.User], RandomKey[dto.User]} {
+		if a, b := key(dto.User{}), key(dto.User{}); string(a) == string(b) {
+			t.Errorf("got the same key %q twice", a)
+		}
+	}
+}
+
+func TestFieldKey(t *testing.T) {
+	msg := order{ID: 42, Paid: true, Price: 9.5, Address: address{City: "Paris", Zip: 75001}}
+	tests := []struct {
+		path string
+		want string
+		err  error
+	}{
+		{"id", "42", nil},
+		{"Paid", "true", nil},
+		{"Price", "9.5", nil},
+		{"address.city", "Paris", nil},
+		{"order.Address.Zip", "75001", nil},
+		{"Tags", "", ErrKeyField},
+		{"Address", "", ErrKeyField},
+		{"Note", "", ErrKeyField},
+		{"id.value", "", ErrKeyField},
+		{"missing", "", ErrKeyField},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			key, err := FieldKey[order](tt.path)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err == nil && string(key(msg)) != tt.want {
+				t.Errorf("got key %q, want %q", key(msg), tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomKey(t *testing.T) {
+	key, err := NewKeyFunc[dto.User](config.KeyConfig{Strategy: config.KeyStrategyCustom})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Broker[dto.User, *dto.User]{key: key}
+	user := dto.User{Name: "Alice"}
+	if _, err := b.Key(user); !errors.Is(err, ErrNoKeyFunc) {
+		t.Errorf("got %v before SetKeyFunc, want %v", err, ErrNoKeyFunc)
+	}
+	if err := b.Send(context.Background(), user, "users"); !errors.Is(err, ErrNoKeyFunc) {
+		t.Errorf("got %v sending before SetKeyFunc, want %v", err, ErrNoKeyFunc)
+	}
+
+	b.SetKeyFunc(func(msg dto.User) []byte { return []byte("user-" + msg.Name) })
+	got, err := b.Key(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "user-Alice" {
+		t.Errorf("got key %q, want %q", got, "user-Alice")
+	}
+}
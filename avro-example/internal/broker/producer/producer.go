@@ -1,20 +1,67 @@
 package producer
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
 
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/clientconfig"
 	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
 	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/metrics"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/tracing"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/validation"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
 	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde"
 	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/avro"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
-type Broker struct {
+var (
+	ErrSerialization = errors.New("message serialization failed")
+	// ErrValidation wraps *validation.Error listing invalid fields of message
+	ErrValidation      = errors.New("message validation failed")
+	ErrQueueFull       = errors.New("producer queue is full")
+	ErrMessageTooLarge = errors.New("message is too large")
+	ErrUnknownTopic    = errors.New("unknown topic or partition")
+	ErrProduce         = errors.New("producing message failed")
+	// ErrClosed is reported for messages whose delivery report didn't arrive before Close
+	ErrClosed      = errors.New("producer is closed")
+	ErrUndelivered = errors.New("messages left undelivered")
+	// ErrFatal means producer can't be used anymore (e.g. idempotence guarantee is broken
+	// or producer is fenced by another one with the same transactional.id) and has to be recreated
+	ErrFatal = errors.New("producer failed fatally")
+)
+
+// Broker sends gogen-avro records of type T, PT is inferred as *T.
+type Broker[T any, PT dto.AvroRecord[T]] struct {
 	producer   *kafka.Producer
 	serializer serde.Serializer
 	log        *slog.Logger
+	// how long Produce is retried while local queue is full
+	queueFullTimeout time.Duration
+	// how long Close waits for outstanding deliveries
+	flushTimeout time.Duration
+	tracer       trace.Tracer
+	registry     schemaregistry.Client
+	// key returns message key for Send
+	key KeyFunc[T]
+	// validator checks messages before they are serialized
+	validator *validation.Validator[T, PT]
+	// partition is kafka.PartitionAny unless explicit partitioner is configured
+	partition int32
+	// fatal is set once kafka reports fatal error, producer fails all sends after that
+	fatal atomic.Pointer[kafka.Error]
+	// done is closed by Close, delivery reports aren't awaited after that
+	done chan struct{}
 }
 
 type Response struct {
@@ -22,16 +69,48 @@ type Response struct {
 	Err      error
 }
 
-var FlushBrokerTimeMs = 100
+// Delivery is the final delivery report of a single message.
+type Delivery struct {
+	TopicPartition kafka.TopicPartition
+	Err            error
+}
+
+const tracerName = "github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/producer"
+
+// QueueFullRetryMs is how long to wait for the local queue to drain
+// between two Produce attempts.
+var QueueFullRetryMs = 100
 
 // New returns kafka producer with schema registry
-func New(cfg *config.Config, log *slog.Logger) (*Broker, error) {
-	p, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": cfg.Kafka.KafkaURL})
+func New[T any, PT dto.AvroRecord[T]](cfg *config.Config, log *slog.Logger) (*Broker[T, PT], error) {
+	configMap, err := clientconfig.Producer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newBroker[T, PT](cfg, log, configMap)
+}
+
+// newBroker returns kafka producer created with configMap
+func newBroker[T any, PT dto.AvroRecord[T]](cfg *config.Config, log *slog.Logger, configMap *kafka.ConfigMap) (*Broker[T, PT], error) {
+	key, err := NewKeyFunc[T](cfg.Kafka.Key)
+	if err != nil {
+		return nil, err
+	}
+	validator, err := validation.New[T, PT](cfg.Validation.Rules)
+	if err != nil {
+		return nil, err
+	}
+	partition := kafka.PartitionAny
+	if cfg.Kafka.Partitioner == config.PartitionerExplicit {
+		partition = cfg.Kafka.Partition
+	}
+
+	p, err := kafka.NewProducer(configMap)
 	if err != nil {
 		return nil, err
 	}
 
-	client, err := schemaregistry.NewClient(schemaregistry.NewConfig(cfg.Kafka.SchemaRegistryURL))
+	client, err := clientconfig.SchemaRegistryClient(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -40,46 +119,78 @@ func New(cfg *config.Config, log *slog.Logger) (*Broker, error) {
 		return nil, err
 	}
 
-	// Delivery report handler for produced messages
-	go func() {
-		for {
-			for e := range p.Events() {
-				switch e := e.(type) {
-				// https://github.com/confluentinc/confluent-kafka-go/blob/master/examples/producer_example/producer_example.go
-				case *kafka.Message:
-					// The message delivery report, indicating success or
-					// permanent failure after retries have been exhausted.
-					// Application level retries won't help since the client
-					// is already configured to do that.
-					if e.TopicPartition.Error != nil {
-						log.Error("sending message finished with failure", "err", e.TopicPartition.Error, "key", string(e.Key))
-						continue
-					}
-					log.Debug("sending message finished with success ", "key", string(e.Key))
-				case kafka.Error:
-					// Generic client instance-level errors, such as
-					// broker connection failures, authentication issues, etc.
-					//
-					// These errors should generally be considered informational
-					// as the underlying client will automatically try to
-					// recover from any errors encountered, the application
-					// does not need to take action on them.
-					log.Error("kafka general error", "err", e.Error())
-				}
+	b := &Broker[T, PT]{
+		producer:         p,
+		serializer:       ser,
+		log:              log,
+		queueFullTimeout: cfg.Kafka.QueueFullTimeout,
+		flushTimeout:     cfg.Kafka.FlushTimeout,
+		tracer:           otel.Tracer(tracerName),
+		registry:         client,
+		key:              key,
+		validator:        validator,
+		partition:        partition,
+		done:             make(chan struct{}),
+	}
+	go b.handleEvents()
+	return b, nil
+}
+
+// handleEvents logs delivery reports of produced messages and client errors
+// until producer is closed. Fatal errors move broker to failed state.
+func (b *Broker[T, PT]) handleEvents() {
+	for e := range b.producer.Events() {
+		switch e := e.(type) {
+		// https://github.com/confluentinc/confluent-kafka-go/blob/master/examples/producer_example/producer_example.go
+		case *kafka.Message:
+			// The message delivery report, indicating success or
+			// permanent failure after retries have been exhausted.
+			// Application level retries won't help since the client
+			// is already configured to do that.
+			if e.TopicPartition.Error != nil {
+				b.log.Error("sending message finished with failure", "err", e.TopicPartition.Error, "key", string(e.Key))
+				continue
+			}
+			b.log.Debug("sending message finished with success ", "key", string(e.Key))
+		case kafka.Error:
+			// Fatal errors (e.g. idempotent producer lost messages
+			// and can't guarantee ordering anymore) can't be recovered from,
+			// the producer has to be recreated.
+			if e.IsFatal() {
+				b.fatal.CompareAndSwap(nil, &e)
+				b.log.Error("kafka fatal error, producer failed", "code", e.Code(), "err", e.Error())
+				continue
+			}
+			// Generic client instance-level errors, such as
+			// broker connection failures, authentication issues, etc.
+			//
+			// These errors should generally be considered informational
+			// as the underlying client will automatically try to
+			// recover from any errors encountered, the application
+			// does not need to take action on them.
+			b.log.Error("kafka general error", "err", e.Error())
+		case *kafka.Stats:
+			// statistics are emitted every statistics.interval.ms
+			err := metrics.ObserveProducerStats(e.String())
+			if err != nil {
+				b.log.Error("parsing kafka statistics failed", "err", err.Error())
 			}
 		}
-	}()
+	}
+}
 
-	return &Broker{
-			producer:   p,
-			serializer: ser,
-			log:        log,
-		},
-		nil
+// Err returns ErrFatal if producer failed and can't send messages anymore
+func (b *Broker[T, PT]) Err() error {
+	fatal := b.fatal.Load()
+	if fatal == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrFatal, *fatal)
 }
 
-// Close closes serialization agent and kafka producer
-func (b *Broker) Close() {
+// Close closes serialization agent and kafka producer.
+// It returns ErrUndelivered if some messages weren't delivered within flushTimeout.
+func (b *Broker[T, PT]) Close() error {
 	b.log.Info("kafka stops")
 	b.serializer.Close()
 	//https://docs.confluent.io/platform/current/clients/confluent-kafka-go/index.html#hdr-Producer
@@ -89,25 +200,234 @@ func (b *Broker) Close() {
 	//lingering in internal channels or transmission queues.
 	//Call the convenience function `.Flush()` will block code until all
 	//message deliveries are done or the provided timeout elapses.
-	b.producer.Flush(FlushBrokerTimeMs)
+	remaining := b.producer.Flush(int(b.flushTimeout.Milliseconds()))
 	b.producer.Close()
+	close(b.done)
+	if remaining > 0 {
+		return fmt.Errorf("%w: %d", ErrUndelivered, remaining)
+	}
+	return nil
 }
 
-// Send sends serialized message to kafka using schema registry
-func (b *Broker) Send(msg dto.User, topic string, key string) error {
-	b.log.Info("sending message", "msg", msg)
-	payload, err := b.serializer.Serialize(topic, &msg)
+// Send sends serialized message to kafka using schema registry with key
+// chosen by the key strategy. It returns as soon as the message is queued,
+// delivery report is logged.
+func (b *Broker[T, PT]) Send(ctx context.Context, msg T, topic string) error {
+	key, err := b.Key(msg)
 	if err != nil {
 		return err
 	}
-	err = b.producer.Produce(&kafka.Message{
-		Key:            []byte(key),
-		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
-		Value:          payload,
-		Headers:        []kafka.Header{{Key: "Course", Value: []byte("Kafka")}},
-	}, nil)
+	_, err = b.SendAsync(ctx, msg, topic, key)
+	return err
+}
+
+// SendAsync validates message and sends it serialized with the given key (nil for no key)
+// to kafka. It returns a channel which receives exactly one delivery report for this message.
+// Trace context of ctx is injected into message headers.
+func (b *Broker[T, PT]) SendAsync(ctx context.Context, msg T, topic string, key []byte) (<-chan Delivery, error) {
+	b.log.Info("sending message", "schema", PT(&msg).SchemaName(), "msg", msg)
+	// https://opentelemetry.io/docs/specs/semconv/messaging/kafka/
+	ctx, span := b.tracer.Start(
+		ctx,
+		"publish "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingOperationName("publish"),
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingKafkaMessageKey(string(key)),
+		),
+	)
+	err := b.validate(msg, topic)
 	if err != nil {
+		tracing.EndSpan(span, err)
+		return nil, err
+	}
+	kafkaMsg, err := b.message(ctx, msg, topic, key)
+	if err != nil {
+		tracing.EndSpan(span, err)
+		return nil, err
+	}
+	result, err := b.deliver(ctx, kafkaMsg, span)
+	if err != nil {
+		tracing.EndSpan(span, err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// SendSync sends serialized message to kafka and blocks until the delivery
+// report arrives or ctx is done. It returns partition and offset of the message.
+func (b *Broker[T, PT]) SendSync(ctx context.Context, msg T, topic string, key []byte) (kafka.TopicPartition, error) {
+	result, err := b.SendAsync(ctx, msg, topic, key)
+	if err != nil {
+		return kafka.TopicPartition{}, err
+	}
+	return wait(ctx, result)
+}
+
+// SendRaw sends already serialized message as is (e.g. to forward it to
+// another topic) and blocks until its delivery report arrives or ctx is done.
+func (b *Broker[T, PT]) SendRaw(ctx context.Context, msg *kafka.Message) (kafka.TopicPartition, error) {
+	result, err := b.deliver(ctx, msg, noop.Span{})
+	if err != nil {
+		return kafka.TopicPartition{}, err
+	}
+	return wait(ctx, result)
+}
+
+// deliver produces message with a private delivery channel, so the report
+// doesn't go to the shared Events loop. span is ended when the report arrives.
+func (b *Broker[T, PT]) deliver(ctx context.Context, msg *kafka.Message, span trace.Span) (<-chan Delivery, error) {
+	deliveryChan := make(chan kafka.Event, 1)
+	err := b.produce(ctx, msg, deliveryChan)
+	if err != nil {
+		return nil, err
+	}
+	topic := *msg.TopicPartition.Topic
+	producedAt := time.Now()
+
+	result := make(chan Delivery, 1)
+	go func() {
+		defer close(result)
+		var e kafka.Event
+		select {
+		case e = <-deliveryChan:
+		case <-b.done:
+			// report may arrive right before producer is closed
+			select {
+			case e = <-deliveryChan:
+			default:
+			}
+		}
+		switch ev := e.(type) {
+		case nil:
+			tracing.EndSpan(span, ErrClosed)
+			result <- Delivery{Err: ErrClosed}
+		case *kafka.Message:
+			if ev.TopicPartition.Error != nil {
+				b.log.Error("sending message finished with failure", "err", ev.TopicPartition.Error, "key", string(ev.Key))
+			} else {
+				b.log.Debug("sending message finished with success ", "key", string(ev.Key))
+			}
+			metrics.DeliveryDuration.WithLabelValues(topic).Observe(time.Since(producedAt).Seconds())
+			var err error
+			if ev.TopicPartition.Error != nil {
+				err = produceError(ev.TopicPartition.Error)
+				metrics.DeliveryFailures.WithLabelValues(topic, errorCode(ev.TopicPartition.Error)).Inc()
+			} else {
+				metrics.MessagesSent.WithLabelValues(topic).Inc()
+			}
+			span.SetAttributes(
+				semconv.MessagingDestinationPartitionID(strconv.Itoa(int(ev.TopicPartition.Partition))),
+				semconv.MessagingKafkaMessageOffset(int(ev.TopicPartition.Offset)),
+			)
+			tracing.EndSpan(span, err)
+			result <- Delivery{TopicPartition: ev.TopicPartition, Err: err}
+		case kafka.Error:
+			err := produceError(ev)
+			metrics.DeliveryFailures.WithLabelValues(topic, ev.Code().String()).Inc()
+			tracing.EndSpan(span, err)
+			result <- Delivery{Err: err}
+		}
+	}()
+	return result, nil
+}
+
+// wait blocks until delivery report arrives or ctx is done
+func wait(ctx context.Context, result <-chan Delivery) (kafka.TopicPartition, error) {
+	select {
+	case <-ctx.Done():
+		return kafka.TopicPartition{}, ctx.Err()
+	case d := <-result:
+		return d.TopicPartition, d.Err
+	}
+}
+
+// validate checks msg against validation rules, so invalid messages never reach kafka
+func (b *Broker[T, PT]) validate(msg T, topic string) error {
+	err := b.validator.Validate(msg)
+	if err == nil {
 		return nil
 	}
-	return nil
+	var invalid *validation.Error
+	if errors.As(err, &invalid) {
+		for _, f := range invalid.Fields {
+			metrics.ValidationFailures.WithLabelValues(topic, f.Field).Inc()
+		}
+	}
+	return fmt.Errorf("%w: %w", ErrValidation, err)
+}
+
+// message serializes msg and builds kafka message with trace context headers
+func (b *Broker[T, PT]) message(ctx context.Context, msg T, topic string, key []byte) (*kafka.Message, error) {
+	start := time.Now()
+	payload, err := b.serializer.Serialize(topic, PT(&msg))
+	metrics.SerializationDuration.WithLabelValues(topic).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSerialization, err)
+	}
+	var headers []kafka.Header
+	otel.GetTextMapPropagator().Inject(ctx, tracing.HeadersCarrier{Headers: &headers})
+	return &kafka.Message{
+		Key:            key,
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: b.partition},
+		Value:          payload,
+		Headers:        headers,
+	}, nil
+}
+
+// produce queues message. While the local queue is full it waits for the
+// queue to drain and tries again until queueFullTimeout elapses or ctx is done.
+func (b *Broker[T, PT]) produce(ctx context.Context, msg *kafka.Message, deliveryChan chan kafka.Event) error {
+	err := b.Err()
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(b.queueFullTimeout)
+	for {
+		err := b.producer.Produce(msg, deliveryChan)
+		if err == nil {
+			return nil
+		}
+		var kafkaErr kafka.Error
+		if !errors.As(err, &kafkaErr) || kafkaErr.Code() != kafka.ErrQueueFull {
+			return produceError(err)
+		}
+		if ctx.Err() != nil || !time.Now().Before(deadline) {
+			return produceError(err)
+		}
+		b.log.Warn("producer queue is full, waiting", "queued", b.producer.Len())
+		b.producer.Flush(QueueFullRetryMs)
+	}
+}
+
+// errorCode returns kafka error code name of err
+func errorCode(err error) string {
+	var kafkaErr kafka.Error
+	if errors.As(err, &kafkaErr) {
+		return kafkaErr.Code().String()
+	}
+	return "unknown"
+}
+
+// produceError wraps kafka error with one of package errors,
+// so callers can match it with errors.Is
+func produceError(err error) error {
+	var kafkaErr kafka.Error
+	if errors.As(err, &kafkaErr) {
+		if kafkaErr.IsFatal() {
+			return fmt.Errorf("%w: %w", ErrFatal, err)
+		}
+		switch kafkaErr.Code() {
+		case kafka.ErrQueueFull:
+			return fmt.Errorf("%w: %w", ErrQueueFull, err)
+		case kafka.ErrMsgSizeTooLarge:
+			return fmt.Errorf("%w: %w", ErrMessageTooLarge, err)
+		case kafka.ErrUnknownTopic, kafka.ErrUnknownTopicOrPart:
+			return fmt.Errorf("%w: %w", ErrUnknownTopic, err)
+		}
+	}
+	return fmt.Errorf("%w: %w", ErrProduce, err)
 }
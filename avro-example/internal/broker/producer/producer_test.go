@@ -0,0 +1,87 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestProduceError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"queue full", kafka.NewError(kafka.ErrQueueFull, "queue full", false), ErrQueueFull},
+		{"message too large", kafka.NewError(kafka.ErrMsgSizeTooLarge, "too large", false), ErrMessageTooLarge},
+		{"unknown topic", kafka.NewError(kafka.ErrUnknownTopic, "unknown topic", false), ErrUnknownTopic},
+		{"unknown partition", kafka.NewError(kafka.ErrUnknownTopicOrPart, "unknown partition", false), ErrUnknownTopic},
+		{"other kafka error", kafka.NewError(kafka.ErrTimedOut, "timed out", false), ErrProduce},
+		{"not kafka error", errors.New("failed"), ErrProduce},
+		// fatal error can't be retried whatever its code is
+		{"fatal", kafka.NewError(kafka.ErrUnknownTopic, "fenced", true), ErrFatal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := produceError(tt.err)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+			// kafka error is kept, so its code is still available
+			if !errors.Is(err, tt.err) {
+				t.Errorf("got %v, want it to wrap %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCloseUndelivered(t *testing.T) {
+	// nothing listens on the port, so messages stay in the queue
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+kafka:
+  kafkaUrl: "127.0.0.1:1"
+  schemaRegistryURL: "mock://close-undelivered"
+  topic: "users"
+  flushTimeout: "100ms"
+`
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadByPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New[dto.User](cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := b.SendAsync(context.Background(), dto.User{Name: "Alice"}, "users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = b.Close()
+	if !errors.Is(err, ErrUndelivered) {
+		t.Errorf("got %v from Close, want %v", err, ErrUndelivered)
+	}
+	// delivery report never arrives, but the waiter isn't left blocked
+	select {
+	case d := <-result:
+		if !errors.Is(d.Err, ErrClosed) {
+			t.Errorf("got %v, want %v", d.Err, ErrClosed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery result isn't reported after Close")
+	}
+}
@@ -0,0 +1,109 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/clientconfig"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+var ErrTransaction = errors.New("transaction failed")
+
+// NewTransactional returns producer sending messages in kafka transactions.
+// transactionalID has to be unique for every running producer instance.
+func NewTransactional[T any, PT dto.AvroRecord[T]](
+	ctx context.Context,
+	cfg *config.Config,
+	log *slog.Logger,
+	transactionalID string,
+) (*Broker[T, PT], error) {
+	configMap, err := clientconfig.Producer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	err = configMap.SetKey("transactional.id", transactionalID)
+	if err != nil {
+		return nil, err
+	}
+	err = configMap.SetKey("transaction.timeout.ms", int(cfg.Kafka.Transaction.Timeout.Milliseconds()))
+	if err != nil {
+		return nil, err
+	}
+	b, err := newBroker[T, PT](cfg, log.With("transactionalId", transactionalID), configMap)
+	if err != nil {
+		return nil, err
+	}
+	// it fences previous producer with the same transactional.id and
+	// aborts its unfinished transactions
+	err = b.producer.InitTransactions(ctx)
+	if err != nil {
+		_ = b.Close()
+		return nil, transactionError(err)
+	}
+	return b, nil
+}
+
+// BeginTransaction starts transaction, messages sent until it is committed
+// or aborted become visible to read_committed consumers atomically
+func (b *Broker[T, PT]) BeginTransaction() error {
+	err := b.producer.BeginTransaction()
+	if err != nil {
+		return transactionError(err)
+	}
+	return nil
+}
+
+// SendOffsetsToTransaction adds consumed offsets to transaction,
+// so they are committed together with produced messages
+func (b *Broker[T, PT]) SendOffsetsToTransaction(
+	ctx context.Context,
+	offsets []kafka.TopicPartition,
+	metadata *kafka.ConsumerGroupMetadata,
+) error {
+	err := b.producer.SendOffsetsToTransaction(ctx, offsets, metadata)
+	if err != nil {
+		return transactionError(err)
+	}
+	return nil
+}
+
+// CommitTransaction flushes messages of transaction and commits it.
+// Retriable errors are retried until ctx is done,
+// on other errors transaction has to be aborted.
+func (b *Broker[T, PT]) CommitTransaction(ctx context.Context) error {
+	for {
+		err := b.producer.CommitTransaction(ctx)
+		if err == nil {
+			return nil
+		}
+		var kafkaErr kafka.Error
+		if !errors.As(err, &kafkaErr) || !kafkaErr.IsRetriable() || ctx.Err() != nil {
+			return transactionError(err)
+		}
+		b.log.Warn("committing transaction failed, retrying", "err", err.Error())
+	}
+}
+
+// AbortTransaction drops messages of transaction, consumed offsets
+// aren't committed, so the messages are consumed again
+func (b *Broker[T, PT]) AbortTransaction(ctx context.Context) error {
+	err := b.producer.AbortTransaction(ctx)
+	if err != nil {
+		return transactionError(err)
+	}
+	return nil
+}
+
+// transactionError wraps kafka error with ErrFatal or ErrTransaction
+func transactionError(err error) error {
+	var kafkaErr kafka.Error
+	if errors.As(err, &kafkaErr) && kafkaErr.IsFatal() {
+		return fmt.Errorf("%w: %w", ErrFatal, err)
+	}
+	return fmt.Errorf("%w: %w", ErrTransaction, err)
+}
@@ -5,6 +5,10 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -12,6 +16,34 @@ import (
 var (
 	ErrAbsentConfigFile = errors.New("config file does not exists")
 	ErrReadConfigFailed = errors.New("reading config file failed")
+	ErrInvalidConfig    = errors.New("invalid config")
+)
+
+// producer key strategies
+const (
+	// KeyStrategyField uses value of record field as key
+	KeyStrategyField = "field"
+	// KeyStrategyUUID and KeyStrategyRandom use random keys
+	KeyStrategyUUID   = "uuid"
+	KeyStrategyRandom = "random"
+	// KeyStrategyNull sends messages without key
+	KeyStrategyNull = "null"
+	// KeyStrategyCustom uses function set by application
+	KeyStrategyCustom = "custom"
+)
+
+// PartitionerExplicit sends all messages to the configured partition
+const PartitionerExplicit = "explicit"
+
+var (
+	autoOffsetResetValues = []string{"earliest", "latest", "none"}
+	assignors             = []string{"range", "roundrobin", "cooperative-sticky"}
+	keyStrategies         = []string{KeyStrategyField, KeyStrategyUUID, KeyStrategyRandom, KeyStrategyNull, KeyStrategyCustom}
+	partitioners          = []string{
+		"random", "consistent", "consistent_random", "murmur2", "murmur2_random", "fnv1a", "fnv1a_random",
+		PartitionerExplicit,
+	}
+	traceExporters = []string{"none", "stdout", "otlp"}
 )
 
 type KafkaConfig struct {
@@ -19,31 +51,387 @@ type KafkaConfig struct {
 	SchemaRegistryURL string `yaml:"schemaRegistryURL" env-required:"true"`
 	Type              string
 	Topic             string `yaml:"topic" env-required:"true"`
+	// consumer subscribes to Topics or to topics matching TopicPattern,
+	// if none of them is set, consumer subscribes to Topic
+	Topics       []string `yaml:"topics"`
+	TopicPattern string   `yaml:"topicPattern"`
+	GroupID      string   `yaml:"groupId" env-default:"1"`
+	// earliest, latest or none
+	AutoOffsetReset string        `yaml:"autoOffsetReset" env-default:"earliest"`
+	SessionTimeout  time.Duration `yaml:"sessionTimeout" env-default:"6s"`
+	// comma separated list of range, roundrobin or cooperative-sticky,
+	// cooperative-sticky can't be combined with the others
+	AssignmentStrategy string        `yaml:"assignmentStrategy" env-default:"range,roundrobin"`
+	PollTimeout        time.Duration `yaml:"pollTimeout" env-default:"100ms"`
+	// idempotent producer doesn't duplicate or reorder messages on retries,
+	// when it isn't set, producer is idempotent in prod env only
+	Idempotence *bool `yaml:"idempotence"`
+	// how producer chooses message keys
+	Key KeyConfig `yaml:"key"`
+	// librdkafka partitioner or explicit
+	Partitioner string `yaml:"partitioner" env-default:"consistent_random"`
+	// partition all messages are sent to by explicit partitioner
+	Partition int32 `yaml:"partition"`
+	// how long producer waits for local queue to drain when it is full
+	QueueFullTimeout time.Duration `yaml:"queueFullTimeout" env-default:"5s"`
+	// how long producer waits for outstanding deliveries on shutdown
+	FlushTimeout time.Duration `yaml:"flushTimeout" env-default:"10s"`
+	// consumer commits offsets after this number of handled messages
+	CommitBatchSize int `yaml:"commitBatchSize" env-default:"100"`
+	// or after this interval, whichever comes first
+	CommitInterval time.Duration `yaml:"commitInterval" env-default:"5s"`
+	DLQ            DLQConfig     `yaml:"dlq"`
+	Retry          RetryConfig   `yaml:"retry"`
+	Batch          BatchConfig   `yaml:"batch"`
+	Workers        WorkersConfig `yaml:"workers"`
+	// transformer settings
+	Transaction TransactionConfig `yaml:"transaction"`
+	// authentication and encryption of kafka and schema registry clients
+	Security           SecurityConfig           `yaml:"security"`
+	SchemaRegistryAuth SchemaRegistryAuthConfig `yaml:"schemaRegistryAuth"`
+}
+
+type KeyConfig struct {
+	// field, uuid, random, null or custom
+	Strategy string `yaml:"strategy" env-default:"null"`
+	// record field used as key by field strategy, e.g. User.Name
+	Field string `yaml:"field"`
+}
+
+type DLQConfig struct {
+	// messages which can't be deserialized or handled are sent to dead letter topic
+	Enabled bool `yaml:"enabled" env-default:"false"`
+	// dead letter topic name is source topic name plus suffix
+	Suffix string `yaml:"suffix" env-default:".DLQ"`
+}
+
+type RetryConfig struct {
+	// messages failed with retryable error are sent to <topic>.retry.N topics
+	Enabled bool `yaml:"enabled" env-default:"false"`
+	// number of retry topics, after the last one message goes to dead letter topic
+	Attempts int `yaml:"attempts" env-default:"3"`
+	// delay before the first retry, each next one is Multiplier times longer
+	Backoff    time.Duration `yaml:"backoff" env-default:"1s"`
+	Multiplier float64       `yaml:"multiplier" env-default:"2"`
+	// it has to be less than max.poll.interval.ms, as retry consumer waits without polling
+	MaxBackoff time.Duration `yaml:"maxBackoff" env-default:"1m"`
+}
+
+type BatchConfig struct {
+	// consumer passes records to handler in batches, offsets are committed after each batch
+	Enabled bool `yaml:"enabled" env-default:"false"`
+	// maximum number of records in batch
+	Size int `yaml:"size" env-default:"100"`
+	// maximum time of collecting batch
+	Timeout time.Duration `yaml:"timeout" env-default:"1s"`
+}
+
+type WorkersConfig struct {
+	// each assigned partition is handled by its own goroutines, partitions are handled concurrently
+	Enabled bool `yaml:"enabled" env-default:"false"`
+	// messages of partition are spread over this number of workers by key hash,
+	// messages with the same key are handled in order
+	PerPartition int `yaml:"perPartition" env-default:"1"`
+	// partition is paused when this number of its messages are in flight
+	QueueSize int `yaml:"queueSize" env-default:"100"`
+}
+
+type TransactionConfig struct {
+	// transactional.id of transformer producer, it has to be unique for every running instance
+	ID string `yaml:"id" env:"KAFKA_TRANSACTIONAL_ID"`
+	// topic transformed messages are produced to
+	OutputTopic string `yaml:"outputTopic"`
+	// transaction which isn't committed within timeout is aborted by kafka
+	Timeout time.Duration `yaml:"timeout" env-default:"60s"`
+}
+
+type TracingConfig struct {
+	// none, stdout or otlp
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none"`
+	// otlp http endpoint, e.g. http://localhost:4318/v1/traces
+	Endpoint    string `yaml:"endpoint" env:"TRACING_ENDPOINT"`
+	ServiceName string `yaml:"serviceName" env-default:"kafka-avro"`
+}
+
+type MonitoringConfig struct {
+	// http server exposing /metrics, /healthz and /readyz
+	Enabled bool   `yaml:"enabled" env:"MONITORING_ENABLED" env-default:"false"`
+	Address string `yaml:"address" env:"MONITORING_ADDRESS" env-default:":9090"`
+	// librdkafka statistics interval, statistics are used for consumer lag, 0 disables them
+	StatsInterval time.Duration `yaml:"statsInterval" env-default:"15s"`
+	// consumer isn't alive when it hasn't polled kafka for this time
+	PollStaleness time.Duration `yaml:"pollStaleness" env-default:"30s"`
+}
+
+type ValidationConfig struct {
+	// rules by avro field name, they override rules from "validation" attributes of schema fields
+	Rules map[string]FieldRule `yaml:"rules"`
+}
+
+// FieldRule is a set of checks of record field value, unset checks are skipped
+type FieldRule struct {
+	// string or bytes must not be empty, null is never allowed
+	NotEmpty bool `yaml:"notEmpty" json:"notEmpty"`
+	// string must match regular expression
+	Pattern string `yaml:"pattern" json:"pattern"`
+	// number must be in range [Min, Max]
+	Min *float64 `yaml:"min" json:"min"`
+	Max *float64 `yaml:"max" json:"max"`
+	// string must be one of the values
+	Enum []string `yaml:"enum" json:"enum"`
+}
+
+type GatewayConfig struct {
+	// http server accepting POST /topics/{topic}
+	Address string `yaml:"address" env:"GATEWAY_ADDRESS" env-default:":8080"`
+	// topics messages may be sent to, kafka.topic is used when it is empty
+	Topics []string `yaml:"topics"`
+	// header holding message key, key strategy is used when request has no such header
+	KeyHeader string `yaml:"keyHeader" env-default:"X-Message-Key"`
+	// requests with larger body are rejected
+	MaxBodySize int64 `yaml:"maxBodySize" env-default:"1048576"`
+	// how long request waits for delivery report
+	DeliveryTimeout time.Duration `yaml:"deliveryTimeout" env-default:"30s"`
+	// how long gateway waits for in-flight requests on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env-default:"10s"`
+}
+
+type GRPCConfig struct {
+	// grpc server exposing Produce, ProduceStream and Subscribe
+	Address string `yaml:"address" env:"GRPC_ADDRESS" env-default:":50051"`
+	// topics messages may be produced to or subscribed to, kafka.topic is used when it is empty
+	Topics []string `yaml:"topics"`
+	// how long Produce waits for delivery report
+	DeliveryTimeout time.Duration `yaml:"deliveryTimeout" env-default:"30s"`
+	// how long server waits for in-flight calls on shutdown, subscriptions are ended at once
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env-default:"10s"`
+}
+
+type InputConfig struct {
+	// format of producer input: interactive, jsonl, csv or avro-json
+	Format string `yaml:"format" env-default:"interactive"`
+	// file input is read from, stdin is used when it is empty or "-"
+	File string `yaml:"file"`
+	// maps csv columns to record fields, columns missing in it are matched by their own name
+	CSVMapping map[string]string `yaml:"csvMapping"`
+}
+
+type OutputConfig struct {
+	// how consumer writes records to stdout: log, jsonl, avro-json, csv, table or avro
+	Format string `yaml:"format" env-default:"log"`
+	// metadata columns written before record fields: topic, partition, offset, timestamp, key, headers
+	Columns []string `yaml:"columns"`
 }
 
 type Config struct {
 	// without this param will be used "local" as param value
 	Env   string      `yaml:"env" env-default:"local"`
 	Kafka KafkaConfig `yaml:"kafka"`
+	// producer input, flags -input, -file and -csv-map override it
+	Input InputConfig `yaml:"input"`
+	// consumer output, flags -output and -columns override it
+	Output OutputConfig `yaml:"output"`
+	// checks of records before they are sent
+	Validation ValidationConfig `yaml:"validation"`
+	// http gateway settings
+	Gateway GatewayConfig `yaml:"gateway"`
+	// grpc service settings
+	GRPC GRPCConfig `yaml:"grpc"`
+	// librdkafka properties merged into producer and consumer configs
+	Producer   Properties       `yaml:"producer"`
+	Consumer   Properties       `yaml:"consumer"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Monitoring MonitoringConfig `yaml:"monitoring"`
 }
 
 func (c *Config) String() string {
 	return fmt.Sprintf(
-		"type: %s, env: %s, kafka url %s, schema registry url %s",
-		c.Kafka.Type, c.Env, c.Kafka.KafkaURL, c.Kafka.SchemaRegistryURL,
+		"type: %s, env: %s, kafka url %s, schema registry url %s, security protocol %s, idempotent %t, producer %s, consumer %s",
+		c.Kafka.Type, c.Env, c.Kafka.KafkaURL, c.Kafka.SchemaRegistryURL, c.Kafka.Security.Protocol,
+		c.Idempotent(), c.Producer, c.Consumer,
 	)
 }
 
+// Subscription returns topics consumer subscribes to, regex subscription
+// is a single topic starting with "^", it matches whole topic names
+func (k *KafkaConfig) Subscription() []string {
+	if k.TopicPattern != "" {
+		return []string{anchorPattern(k.TopicPattern)}
+	}
+	if len(k.Topics) > 0 {
+		return k.Topics
+	}
+	return []string{k.Topic}
+}
+
+// Idempotent reports whether producer is idempotent
+func (c *Config) Idempotent() bool {
+	if c.Kafka.Idempotence != nil {
+		return *c.Kafka.Idempotence
+	}
+	return c.Env == "prod"
+}
+
+// Validate checks config values, so misconfiguration fails at startup
+func (c *Config) Validate() error {
+	k := &c.Kafka
+	if k.TopicPattern != "" && len(k.Topics) > 0 {
+		return fmt.Errorf("%w: topics and topicPattern are mutually exclusive", ErrInvalidConfig)
+	}
+	if k.TopicPattern != "" {
+		_, err := regexp.Compile(k.TopicPattern)
+		if err != nil {
+			return fmt.Errorf("%w: topicPattern: %w", ErrInvalidConfig, err)
+		}
+		// consumer would read its own retry and dead letter topics as source ones
+		// and send their messages to retry topics again
+		if suffixes := k.derivedSuffixes(); len(suffixes) > 0 {
+			derived, err := matchesSuffix(anchorPattern(k.TopicPattern), suffixes)
+			if err != nil {
+				return fmt.Errorf("%w: topicPattern: %w", ErrInvalidConfig, err)
+			}
+			if derived {
+				return fmt.Errorf(
+					"%w: topicPattern %q matches retry or dead letter topics", ErrInvalidConfig, k.TopicPattern,
+				)
+			}
+		}
+	}
+	for _, topic := range k.Topics {
+		if topic == "" {
+			return fmt.Errorf("%w: empty topic in topics", ErrInvalidConfig)
+		}
+	}
+	if k.GroupID == "" {
+		return fmt.Errorf("%w: groupId is empty", ErrInvalidConfig)
+	}
+	if !slices.Contains(autoOffsetResetValues, k.AutoOffsetReset) {
+		return fmt.Errorf(
+			"%w: autoOffsetReset %q is not one of %v", ErrInvalidConfig, k.AutoOffsetReset, autoOffsetResetValues,
+		)
+	}
+	if err := k.validateAssignmentStrategy(); err != nil {
+		return err
+	}
+	if k.SessionTimeout <= 0 {
+		return fmt.Errorf("%w: sessionTimeout must be positive", ErrInvalidConfig)
+	}
+	if k.PollTimeout <= 0 {
+		return fmt.Errorf("%w: pollTimeout must be positive", ErrInvalidConfig)
+	}
+	if k.Batch.Enabled {
+		if k.Batch.Size <= 0 {
+			return fmt.Errorf("%w: batch.size must be positive", ErrInvalidConfig)
+		}
+		if k.Batch.Timeout <= 0 {
+			return fmt.Errorf("%w: batch.timeout must be positive", ErrInvalidConfig)
+		}
+		if k.Retry.Enabled {
+			return fmt.Errorf("%w: retry topics aren't supported in batch mode", ErrInvalidConfig)
+		}
+	}
+	if !slices.Contains(keyStrategies, k.Key.Strategy) {
+		return fmt.Errorf("%w: key.strategy %q is not one of %v", ErrInvalidConfig, k.Key.Strategy, keyStrategies)
+	}
+	if k.Key.Strategy == KeyStrategyField && k.Key.Field == "" {
+		return fmt.Errorf("%w: key.field is required by field key strategy", ErrInvalidConfig)
+	}
+	if !slices.Contains(partitioners, k.Partitioner) {
+		return fmt.Errorf("%w: partitioner %q is not one of %v", ErrInvalidConfig, k.Partitioner, partitioners)
+	}
+	if k.Partitioner == PartitionerExplicit && k.Partition < 0 {
+		return fmt.Errorf("%w: partition must not be negative", ErrInvalidConfig)
+	}
+	if k.Transaction.Timeout <= 0 {
+		return fmt.Errorf("%w: transaction.timeout must be positive", ErrInvalidConfig)
+	}
+	if k.Workers.Enabled {
+		if k.Workers.PerPartition <= 0 {
+			return fmt.Errorf("%w: workers.perPartition must be positive", ErrInvalidConfig)
+		}
+		if k.Workers.QueueSize <= 0 {
+			return fmt.Errorf("%w: workers.queueSize must be positive", ErrInvalidConfig)
+		}
+		if k.Batch.Enabled {
+			return fmt.Errorf("%w: workers and batch are mutually exclusive", ErrInvalidConfig)
+		}
+	}
+	if c.Gateway.MaxBodySize <= 0 {
+		return fmt.Errorf("%w: gateway.maxBodySize must be positive", ErrInvalidConfig)
+	}
+	if c.Gateway.DeliveryTimeout <= 0 {
+		return fmt.Errorf("%w: gateway.deliveryTimeout must be positive", ErrInvalidConfig)
+	}
+	if c.GRPC.DeliveryTimeout <= 0 {
+		return fmt.Errorf("%w: grpc.deliveryTimeout must be positive", ErrInvalidConfig)
+	}
+	if !slices.Contains(traceExporters, c.Tracing.Exporter) {
+		return fmt.Errorf("%w: tracing.exporter %q is not one of %v", ErrInvalidConfig, c.Tracing.Exporter, traceExporters)
+	}
+	if c.Tracing.Exporter == "otlp" && c.Tracing.Endpoint == "" {
+		return fmt.Errorf("%w: tracing.endpoint is required by otlp exporter", ErrInvalidConfig)
+	}
+	if err := c.validateSecurity(); err != nil {
+		return err
+	}
+	if err := c.Producer.validate("producer", producerManaged); err != nil {
+		return err
+	}
+	if c.Idempotent() {
+		if err := c.Producer.validateIdempotence(); err != nil {
+			return err
+		}
+	}
+	if err := c.Consumer.validate("consumer", consumerManaged); err != nil {
+		return err
+	}
+	// retry consumer waits for retry time without polling, unless messages are handled by workers
+	if k.Retry.Enabled && !k.Workers.Enabled && k.Retry.MaxBackoff >= c.Consumer.maxPollInterval() {
+		return fmt.Errorf(
+			"%w: retry.maxBackoff %s must be less than consumer max.poll.interval.ms %s",
+			ErrInvalidConfig, k.Retry.MaxBackoff, c.Consumer.maxPollInterval(),
+		)
+	}
+	return nil
+}
+
+// validateAssignmentStrategy checks assignors, librdkafka doesn't allow
+// to mix cooperative assignor with eager ones
+func (k *KafkaConfig) validateAssignmentStrategy() error {
+	strategies := strings.Split(k.AssignmentStrategy, ",")
+	for i, strategy := range strategies {
+		strategies[i] = strings.TrimSpace(strategy)
+		if !slices.Contains(assignors, strategies[i]) {
+			return fmt.Errorf(
+				"%w: assignmentStrategy %q is not one of %v", ErrInvalidConfig, strategies[i], assignors,
+			)
+		}
+	}
+	if len(strategies) > 1 && slices.Contains(strategies, "cooperative-sticky") {
+		return fmt.Errorf("%w: cooperative-sticky can't be combined with other assignors", ErrInvalidConfig)
+	}
+	return nil
+}
+
 // New loads config
 func New() (*Config, error) {
 	cfg := &Config{}
 	var err error
 	var configPath string
 	var kafkaClientType string
-	// kafka client type  - producer or consumer
+	var inputFormat, inputFile, csvMapping string
+	var outputFormat, columns string
+	// kafka client type  - producer, consumer, transformer, gateway or grpc
 	flag.StringVar(&kafkaClientType, "t", "producer", "type of kafka client")
 	// path to config yaml file
 	flag.StringVar(&configPath, "c", "", "path to config file")
+	// producer input
+	flag.StringVar(&inputFormat, "input", "", "producer input format: interactive, jsonl, csv or avro-json")
+	flag.StringVar(&inputFile, "file", "", "file producer input is read from, - is stdin")
+	flag.StringVar(&csvMapping, "csv-map", "", "csv columns to record fields mapping, e.g. user_name=name,color=favorite_color")
+	// consumer output
+	flag.StringVar(&outputFormat, "output", "", "consumer output format: log, jsonl, avro-json, csv, table or avro")
+	flag.StringVar(&columns, "columns", "", "metadata columns of consumer output, e.g. topic,partition,offset,timestamp,key,headers")
 	flag.Parse()
 	if configPath == "" {
 		configPath = os.Getenv("CONFIG_PATH")
@@ -54,11 +442,51 @@ func New() (*Config, error) {
 			return nil, err
 		}
 		cfg.Kafka.Type = kafkaClientType
+		// built-in applications don't set key function, so custom keys would never be sent
+		if cfg.Kafka.Key.Strategy == KeyStrategyCustom {
+			return nil, fmt.Errorf(
+				"%w: key.strategy %q isn't supported by %s", ErrInvalidConfig, KeyStrategyCustom, kafkaClientType,
+			)
+		}
+		if inputFormat != "" {
+			cfg.Input.Format = inputFormat
+		}
+		if inputFile != "" {
+			cfg.Input.File = inputFile
+		}
+		if outputFormat != "" {
+			cfg.Output.Format = outputFormat
+		}
+		if columns != "" {
+			cfg.Output.Columns = strings.Split(columns, ",")
+			for i, column := range cfg.Output.Columns {
+				cfg.Output.Columns[i] = strings.TrimSpace(column)
+			}
+		}
+		if csvMapping != "" {
+			cfg.Input.CSVMapping, err = parseMapping(csvMapping)
+			if err != nil {
+				return nil, err
+			}
+		}
 		return cfg, nil
 	}
 	return cfg, nil
 }
 
+// parseMapping parses comma separated list of key=value pairs
+func parseMapping(s string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" || strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("%w: %q is not a key=value pair", ErrInvalidConfig, pair)
+		}
+		mapping[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return mapping, nil
+}
+
 // LoadByPath loads config by path
 func LoadByPath(configPath string) (*Config, error) {
 	_, err := os.Stat(configPath)
@@ -72,5 +500,11 @@ func LoadByPath(configPath string) (*Config, error) {
 	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
 		return nil, ErrReadConfigFailed
 	}
+	if err := cfg.resolveSecrets(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
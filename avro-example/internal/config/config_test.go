@@ -0,0 +1,118 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// load returns config loaded from yaml with required settings prepended
+func load(t *testing.T, yaml string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+kafka:
+  kafkaUrl: "localhost:9092"
+  schemaRegistryURL: "http://localhost:8081"
+  topic: "users"
+` + yaml
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return LoadByPath(path)
+}
+
+func TestSubscription(t *testing.T) {
+	tests := []struct {
+		name      string
+		kafka     KafkaConfig
+		want      []string
+		matches   []string
+		unmatched []string
+	}{
+		{
+			name:  "topic",
+			kafka: KafkaConfig{Topic: "users"},
+			want:  []string{"users"},
+		},
+		{
+			name:  "topics",
+			kafka: KafkaConfig{Topic: "users", Topics: []string{"orders", "payments"}},
+			want:  []string{"orders", "payments"},
+		},
+		{
+			name:      "pattern matches whole name",
+			kafka:     KafkaConfig{TopicPattern: "users|orders"},
+			want:      []string{"^(users|orders)$"},
+			matches:   []string{"users", "orders"},
+			unmatched: []string{"users.retry.1", "users.DLQ", "old-orders"},
+		},
+		{
+			name:      "anchored pattern",
+			kafka:     KafkaConfig{TopicPattern: "^events-[a-z]+$"},
+			want:      []string{"^(events-[a-z]+)$"},
+			matches:   []string{"events-users"},
+			unmatched: []string{"events-users.retry.1", "events-users.DLQ"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.kafka.Subscription()
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+			if tt.kafka.TopicPattern == "" {
+				return
+			}
+			re := regexp.MustCompile(got[0])
+			for _, topic := range tt.matches {
+				if !re.MatchString(topic) {
+					t.Errorf("%s doesn't match %s", got[0], topic)
+				}
+			}
+			for _, topic := range tt.unmatched {
+				if re.MatchString(topic) {
+					t.Errorf("%s matches %s", got[0], topic)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateTopicPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		invalid bool
+	}{
+		{"no retry and dlq", `  topicPattern: "users.*"`, false},
+		{"exact names", "  topicPattern: \"users|orders\"\n  retry:\n    enabled: true\n  dlq:\n    enabled: true", false},
+		{"fixed suffix", "  topicPattern: \"events-.*-v1\"\n  retry:\n    enabled: true", false},
+		{"matches retry topics", "  topicPattern: \"users.*\"\n  retry:\n    enabled: true", true},
+		{"matches retry topics by class", "  topicPattern: \"users[.a-z0-9]*\"\n  retry:\n    enabled: true", true},
+		{"matches dlq topics", "  topicPattern: \"users(\\\\.DLQ)?\"\n  dlq:\n    enabled: true", true},
+		{"matches dlq topics ignoring case", "  topicPattern: \"(?i)users\\\\.dlq\"\n  dlq:\n    enabled: true", true},
+		{"custom dlq suffix", "  topicPattern: \"users.*\"\n  dlq:\n    enabled: true\n    suffix: \"-dead\"", true},
+		{"custom dlq suffix isn't matched", "  topicPattern: \"users\\\\.[a-z]+\"\n  dlq:\n    enabled: true\n    suffix: \"-dead\"", false},
+		{"invalid regex", `  topicPattern: "users("`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.yaml)
+			if tt.invalid != (err != nil) {
+				t.Fatalf("got error %v, want invalid %v", err, tt.invalid)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("got %v, want %v", err, ErrInvalidConfig)
+			}
+		})
+	}
+}
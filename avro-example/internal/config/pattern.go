@@ -0,0 +1,160 @@
+package config
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// retryTopicSuffix matches suffix of retry topics <topic>.retry.N
+const retryTopicSuffix = `\.retry\.[0-9]+`
+
+// anchorPattern returns topic pattern matching whole topic names, librdkafka
+// regex doesn't support non-capturing groups, so plain group is used
+func anchorPattern(pattern string) string {
+	return "^(" + strings.TrimSuffix(strings.TrimPrefix(pattern, "^"), "$") + ")$"
+}
+
+// matchesSuffix reports whether regex can match a string ending with one of suffix regexes,
+// it's checked by walking both regex programs in lockstep until both of them match.
+// Word boundaries are assumed to match, so the result may be a false positive for them.
+func matchesSuffix(pattern string, suffixes []string) (bool, error) {
+	p, err := compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	q, err := compile(`^(?s:.*)(?:` + strings.Join(suffixes, "|") + `)$`)
+	if err != nil {
+		return false, err
+	}
+	type state struct {
+		p, q    uint32
+		atStart bool
+	}
+	start := state{p: uint32(p.Start), q: uint32(q.Start), atStart: true}
+	visited := map[state]bool{start: true}
+	queue := []state{start}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		if matchAtEnd(p, s.p, s.atStart) && matchAtEnd(q, s.q, s.atStart) {
+			return true, nil
+		}
+		for _, pi := range closure(p, s.p, s.atStart, false) {
+			for _, qi := range closure(q, s.q, s.atStart, false) {
+				if !intersect(runes(&p.Inst[pi]), runes(&q.Inst[qi])) {
+					continue
+				}
+				next := state{p: p.Inst[pi].Out, q: q.Inst[qi].Out}
+				if !visited[next] {
+					visited[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+func compile(pattern string) (*syntax.Prog, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	return syntax.Compile(re.Simplify())
+}
+
+// closure returns instructions consuming a rune or matching, which are reachable
+// from pc without consuming input, atEnd tells whether input is over
+func closure(prog *syntax.Prog, pc uint32, atStart, atEnd bool) []uint32 {
+	var found []uint32
+	visited := make(map[uint32]bool)
+	stack := []uint32{pc}
+	for len(stack) > 0 {
+		pc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[pc] {
+			continue
+		}
+		visited[pc] = true
+		inst := &prog.Inst[pc]
+		switch inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			stack = append(stack, inst.Out, inst.Arg)
+		case syntax.InstCapture, syntax.InstNop:
+			stack = append(stack, inst.Out)
+		case syntax.InstEmptyWidth:
+			op := syntax.EmptyOp(inst.Arg)
+			if op&(syntax.EmptyBeginLine|syntax.EmptyBeginText) != 0 && !atStart {
+				continue
+			}
+			if op&(syntax.EmptyEndLine|syntax.EmptyEndText) != 0 && !atEnd {
+				continue
+			}
+			stack = append(stack, inst.Out)
+		case syntax.InstMatch, syntax.InstRune, syntax.InstRune1, syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+			found = append(found, pc)
+		}
+	}
+	return found
+}
+
+// matchAtEnd reports whether prog matches when input is over at pc
+func matchAtEnd(prog *syntax.Prog, pc uint32, atStart bool) bool {
+	for _, i := range closure(prog, pc, atStart, true) {
+		if prog.Inst[i].Op == syntax.InstMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// runes returns sorted rune ranges matched by instruction, nil for InstMatch
+func runes(inst *syntax.Inst) []rune {
+	switch inst.Op {
+	case syntax.InstRune1:
+		return []rune{inst.Rune[0], inst.Rune[0]}
+	case syntax.InstRuneAny:
+		return []rune{0, unicode.MaxRune}
+	case syntax.InstRuneAnyNotNL:
+		return []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune}
+	case syntax.InstRune:
+		if len(inst.Rune) != 1 {
+			return inst.Rune
+		}
+		r := inst.Rune[0]
+		ranges := []rune{r, r}
+		if syntax.Flags(inst.Arg)&syntax.FoldCase != 0 {
+			for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+				ranges = append(ranges, f, f)
+			}
+		}
+		return ranges
+	}
+	return nil
+}
+
+// intersect reports whether rune ranges have a common rune
+func intersect(a, b []rune) bool {
+	for i := 0; i+1 < len(a); i += 2 {
+		for j := 0; j+1 < len(b); j += 2 {
+			if a[i] <= b[j+1] && b[j] <= a[i+1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// derivedSuffixes returns regexes of suffixes of topics derived from source topics
+func (k *KafkaConfig) derivedSuffixes() []string {
+	var suffixes []string
+	if k.Retry.Enabled {
+		suffixes = append(suffixes, retryTopicSuffix)
+	}
+	if k.DLQ.Enabled && k.DLQ.Suffix != "" {
+		suffixes = append(suffixes, regexp.QuoteMeta(k.DLQ.Suffix))
+	}
+	return suffixes
+}
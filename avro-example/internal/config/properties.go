@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Properties are librdkafka configuration properties passed to the client as is,
+// see https://github.com/confluentinc/librdkafka/blob/master/CONFIGURATION.md
+type Properties map[string]string
+
+const redacted = "[REDACTED]"
+
+// sensitiveProperties are never printed
+var sensitiveProperties = []string{
+	"sasl.password",
+	"sasl.oauthbearer.client.secret",
+	"sasl.oauthbearer.config",
+	"ssl.key.password",
+	"ssl.key.pem",
+	"ssl.keystore.password",
+	"basic.auth.user.info",
+}
+
+// producerManaged and consumerManaged properties are set from typed config fields
+var (
+	producerManaged = []string{
+		"bootstrap.servers", "transactional.id", "transaction.timeout.ms", "enable.idempotence", "partitioner",
+	}
+	consumerManaged = []string{
+		"bootstrap.servers", "group.id", "auto.offset.reset", "session.timeout.ms",
+		"partition.assignment.strategy",
+		// offsets are committed by the consumer itself
+		"enable.auto.commit", "enable.auto.offset.store",
+	}
+)
+
+// propertyValues lists allowed values of enum properties
+var propertyValues = map[string][]string{
+	"acks":              {"0", "1", "all", "-1"},
+	"compression.type":  {"none", "gzip", "snappy", "lz4", "zstd"},
+	"isolation.level":   {"read_committed", "read_uncommitted"},
+	"security.protocol": {"plaintext", "ssl", "sasl_plaintext", "sasl_ssl"},
+}
+
+// boolProperties and intProperties are checked to have value of the proper type
+var (
+	boolProperties = []string{
+		"enable.idempotence", "enable.partition.eof", "enable.ssl.certificate.verification",
+	}
+	intProperties = []string{
+		"linger.ms", "batch.size", "batch.num.messages", "message.max.bytes", "retries",
+		"max.in.flight.requests.per.connection", "request.timeout.ms", "delivery.timeout.ms",
+		"max.poll.interval.ms", "heartbeat.interval.ms", "fetch.min.bytes", "fetch.max.bytes",
+		"queued.max.messages.kbytes", "statistics.interval.ms",
+	}
+)
+
+// String returns properties sorted by key with sensitive values redacted
+func (p Properties) String() string {
+	keys := make([]string, 0, len(p))
+	for key := range p {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := p[key]
+		if IsSensitive(key) {
+			value = redacted
+		}
+		pairs = append(pairs, key+"="+value)
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// IsSensitive reports whether value of librdkafka property key is a secret
+func IsSensitive(key string) bool {
+	return slices.Contains(sensitiveProperties, key)
+}
+
+// maxIdempotentInFlight is the maximum of max.in.flight.requests.per.connection
+// which keeps ordering of idempotent producer
+const maxIdempotentInFlight = 5
+
+// validateIdempotence checks that producer properties don't break idempotence guarantees
+func (p Properties) validateIdempotence() error {
+	if acks, ok := p["acks"]; ok && strings.ToLower(acks) != "all" && acks != "-1" {
+		return fmt.Errorf("%w: producer.acks must be all for idempotent producer", ErrInvalidConfig)
+	}
+	if inFlight, ok := p["max.in.flight.requests.per.connection"]; ok {
+		n, err := strconv.Atoi(inFlight)
+		if err == nil && n > maxIdempotentInFlight {
+			return fmt.Errorf(
+				"%w: producer.max.in.flight.requests.per.connection must be at most %d for idempotent producer",
+				ErrInvalidConfig, maxIdempotentInFlight,
+			)
+		}
+	}
+	return nil
+}
+
+// defaultMaxPollInterval is librdkafka default of max.poll.interval.ms
+const defaultMaxPollInterval = 300000 * time.Millisecond
+
+// maxPollInterval returns max.poll.interval.ms of consumer,
+// consumer leaves the group when it doesn't poll for longer
+func (p Properties) maxPollInterval() time.Duration {
+	ms, err := strconv.Atoi(p["max.poll.interval.ms"])
+	if err != nil {
+		return defaultMaxPollInterval
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// validate checks that properties don't override managed ones
+// and well-known properties have valid values
+func (p Properties) validate(section string, managed []string) error {
+	for key, value := range p {
+		if slices.Contains(managed, key) {
+			return fmt.Errorf("%w: %s.%s is set from kafka section", ErrInvalidConfig, section, key)
+		}
+		if IsSensitive(key) && value == "" {
+			return fmt.Errorf("%w: %s.%s is empty", ErrInvalidConfig, section, key)
+		}
+		if allowed, ok := propertyValues[key]; ok && !slices.Contains(allowed, strings.ToLower(value)) {
+			return fmt.Errorf("%w: %s.%s %q is not one of %v", ErrInvalidConfig, section, key, value, allowed)
+		}
+		if slices.Contains(boolProperties, key) {
+			if _, err := strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("%w: %s.%s must be boolean: %w", ErrInvalidConfig, section, key, err)
+			}
+		}
+		if slices.Contains(intProperties, key) {
+			if _, err := strconv.Atoi(value); err != nil {
+				return fmt.Errorf("%w: %s.%s must be integer: %w", ErrInvalidConfig, section, key, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPropertiesString(t *testing.T) {
+	p := Properties{
+		"sasl.password":         "s3cret",
+		"ssl.key.password":      "s3cret",
+		"basic.auth.user.info":  "user:s3cret",
+		"linger.ms":             "5",
+		"compression.type":      "zstd",
+		"ssl.keystore.password": "s3cret",
+	}
+	want := "{basic.auth.user.info=[REDACTED], compression.type=zstd, linger.ms=5, " +
+		"sasl.password=[REDACTED], ssl.key.password=[REDACTED], ssl.keystore.password=[REDACTED]}"
+	if got := p.String(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// secrets don't leak when the whole config is logged
+	cfg := &Config{Producer: p, Consumer: Properties{"sasl.oauthbearer.client.secret": "s3cret"}}
+	if got := cfg.String(); strings.Contains(got, "s3cret") {
+		t.Errorf("config %s contains secret", got)
+	}
+}
+
+func TestPropertiesValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties Properties
+		valid      bool
+	}{
+		{"empty", Properties{}, true},
+		{"unknown property is passed as is", Properties{"client.id": "avro"}, true},
+		{"managed", Properties{"group.id": "other"}, false},
+		{"empty secret", Properties{"sasl.password": ""}, false},
+		{"enum", Properties{"acks": "all", "compression.type": "lz4"}, true},
+		{"enum ignores case", Properties{"isolation.level": "READ_COMMITTED"}, true},
+		{"enum unknown value", Properties{"compression.type": "brotli"}, false},
+		{"bool", Properties{"enable.partition.eof": "true"}, true},
+		{"bool invalid", Properties{"enable.partition.eof": "yes"}, false},
+		{"int", Properties{"linger.ms": "10", "max.poll.interval.ms": "60000"}, true},
+		{"int invalid", Properties{"linger.ms": "10ms"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.properties.validate("consumer", consumerManaged)
+			if tt.valid && err != nil {
+				t.Errorf("got %v, want valid", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("got %v, want %v", err, ErrInvalidConfig)
+			}
+		})
+	}
+}
+
+func TestLoadProperties(t *testing.T) {
+	cfg, err := load(t, `
+producer:
+  linger.ms: "5"
+consumer:
+  max.poll.interval.ms: "60000"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Producer["linger.ms"] != "5" || cfg.Consumer["max.poll.interval.ms"] != "60000" {
+		t.Errorf("got producer %s, consumer %s", cfg.Producer, cfg.Consumer)
+	}
+
+	_, err = load(t, `
+producer:
+  bootstrap.servers: "other:9092"
+`)
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("got %v overriding bootstrap.servers, want %v", err, ErrInvalidConfig)
+	}
+}
+
+func TestValidateIdempotence(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties Properties
+		valid      bool
+	}{
+		{"defaults", Properties{}, true},
+		{"acks all", Properties{"acks": "all"}, true},
+		{"acks all ignores case", Properties{"acks": "ALL"}, true},
+		{"acks -1", Properties{"acks": "-1"}, true},
+		{"acks 1", Properties{"acks": "1"}, false},
+		{"acks 0", Properties{"acks": "0"}, false},
+		{"max in flight", Properties{"max.in.flight.requests.per.connection": "5"}, true},
+		{"too many in flight", Properties{"max.in.flight.requests.per.connection": "6"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.properties.validateIdempotence()
+			if tt.valid && err != nil {
+				t.Errorf("got %v, want valid", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("got %v, want %v", err, ErrInvalidConfig)
+			}
+		})
+	}
+}
+
+func TestLoadIdempotent(t *testing.T) {
+	tests := []struct {
+		name       string
+		yaml       string
+		idempotent bool
+		valid      bool
+	}{
+		{"local", "env: local\n", false, true},
+		{"prod", "env: prod\n", true, true},
+		{"explicitly off in prod", "  idempotence: false\nenv: prod\n", false, true},
+		{"explicitly on", "  idempotence: true\n", true, true},
+		{"acks 1 without idempotence", "producer:\n  acks: \"1\"\n", false, true},
+		{"acks 1 with idempotence", "env: prod\nproducer:\n  acks: \"1\"\n", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := load(t, tt.yaml)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidConfig) {
+					t.Errorf("got %v, want %v", err, ErrInvalidConfig)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Idempotent() != tt.idempotent {
+				t.Errorf("got idempotent %t, want %t", cfg.Idempotent(), tt.idempotent)
+			}
+		})
+	}
+}
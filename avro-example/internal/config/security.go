@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+var (
+	securityProtocols = []string{"PLAINTEXT", "SSL", "SASL_PLAINTEXT", "SASL_SSL"}
+	saslMechanisms    = []string{"PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512", "OAUTHBEARER"}
+)
+
+// Secrets (passwords, tokens) may be set in yaml, by env variable or
+// read from file which path is set by *File field (e.g. mounted kubernetes secret).
+
+type SecurityConfig struct {
+	// PLAINTEXT, SSL, SASL_PLAINTEXT or SASL_SSL
+	Protocol string     `yaml:"protocol" env:"KAFKA_SECURITY_PROTOCOL" env-default:"PLAINTEXT"`
+	SASL     SASLConfig `yaml:"sasl"`
+	SSL      SSLConfig  `yaml:"ssl"`
+}
+
+type SASLConfig struct {
+	// PLAIN, SCRAM-SHA-256, SCRAM-SHA-512 or OAUTHBEARER
+	Mechanism    string `yaml:"mechanism" env:"KAFKA_SASL_MECHANISM"`
+	Username     string `yaml:"username" env:"KAFKA_SASL_USERNAME"`
+	Password     string `yaml:"password" env:"KAFKA_SASL_PASSWORD"`
+	PasswordFile string `yaml:"passwordFile" env:"KAFKA_SASL_PASSWORD_FILE"`
+	// OAUTHBEARER token is retrieved with OIDC client credentials flow
+	TokenEndpointURL string `yaml:"tokenEndpointUrl" env:"KAFKA_SASL_OAUTHBEARER_TOKEN_ENDPOINT_URL"`
+	ClientID         string `yaml:"clientId" env:"KAFKA_SASL_OAUTHBEARER_CLIENT_ID"`
+	ClientSecret     string `yaml:"clientSecret" env:"KAFKA_SASL_OAUTHBEARER_CLIENT_SECRET"`
+	ClientSecretFile string `yaml:"clientSecretFile" env:"KAFKA_SASL_OAUTHBEARER_CLIENT_SECRET_FILE"`
+	Scope            string `yaml:"scope" env:"KAFKA_SASL_OAUTHBEARER_SCOPE"`
+}
+
+type SSLConfig struct {
+	CALocation      string `yaml:"caLocation" env:"KAFKA_SSL_CA_LOCATION"`
+	CertLocation    string `yaml:"certLocation" env:"KAFKA_SSL_CERT_LOCATION"`
+	KeyLocation     string `yaml:"keyLocation" env:"KAFKA_SSL_KEY_LOCATION"`
+	KeyPassword     string `yaml:"keyPassword" env:"KAFKA_SSL_KEY_PASSWORD"`
+	KeyPasswordFile string `yaml:"keyPasswordFile" env:"KAFKA_SSL_KEY_PASSWORD_FILE"`
+}
+
+type SchemaRegistryAuthConfig struct {
+	// basic authentication
+	Username     string `yaml:"username" env:"SCHEMA_REGISTRY_USERNAME"`
+	Password     string `yaml:"password" env:"SCHEMA_REGISTRY_PASSWORD"`
+	PasswordFile string `yaml:"passwordFile" env:"SCHEMA_REGISTRY_PASSWORD_FILE"`
+	// or bearer authentication, logical cluster and identity pool are
+	// required by Confluent Cloud only
+	BearerToken     string `yaml:"bearerToken" env:"SCHEMA_REGISTRY_BEARER_TOKEN"`
+	BearerTokenFile string `yaml:"bearerTokenFile" env:"SCHEMA_REGISTRY_BEARER_TOKEN_FILE"`
+	LogicalCluster  string `yaml:"logicalCluster" env:"SCHEMA_REGISTRY_LOGICAL_CLUSTER"`
+	IdentityPoolID  string `yaml:"identityPoolId" env:"SCHEMA_REGISTRY_IDENTITY_POOL_ID"`
+	CALocation      string `yaml:"caLocation" env:"SCHEMA_REGISTRY_CA_LOCATION"`
+	CertLocation    string `yaml:"certLocation" env:"SCHEMA_REGISTRY_CERT_LOCATION"`
+	KeyLocation     string `yaml:"keyLocation" env:"SCHEMA_REGISTRY_KEY_LOCATION"`
+}
+
+// IsSASL reports whether kafka clients authenticate with SASL
+func (s *SecurityConfig) IsSASL() bool {
+	return strings.HasPrefix(strings.ToUpper(s.Protocol), "SASL_")
+}
+
+// resolveSecrets reads secrets from files
+func (c *Config) resolveSecrets() error {
+	k := &c.Kafka
+	secrets := []struct {
+		name  string
+		value *string
+		file  string
+	}{
+		{"security.sasl.password", &k.Security.SASL.Password, k.Security.SASL.PasswordFile},
+		{"security.sasl.clientSecret", &k.Security.SASL.ClientSecret, k.Security.SASL.ClientSecretFile},
+		{"security.ssl.keyPassword", &k.Security.SSL.KeyPassword, k.Security.SSL.KeyPasswordFile},
+		{"schemaRegistryAuth.password", &k.SchemaRegistryAuth.Password, k.SchemaRegistryAuth.PasswordFile},
+		{"schemaRegistryAuth.bearerToken", &k.SchemaRegistryAuth.BearerToken, k.SchemaRegistryAuth.BearerTokenFile},
+	}
+	for _, secret := range secrets {
+		if secret.file == "" {
+			continue
+		}
+		if *secret.value != "" {
+			return fmt.Errorf("%w: both %s and its file are set", ErrInvalidConfig, secret.name)
+		}
+		content, err := os.ReadFile(secret.file)
+		if err != nil {
+			return fmt.Errorf("%w: reading %s file: %w", ErrInvalidConfig, secret.name, err)
+		}
+		*secret.value = strings.TrimRight(string(content), "\r\n")
+	}
+	return nil
+}
+
+// validateSecurity checks that chosen authentication methods have all their settings
+func (c *Config) validateSecurity() error {
+	s := &c.Kafka.Security
+	if !slices.Contains(securityProtocols, strings.ToUpper(s.Protocol)) {
+		return fmt.Errorf("%w: security.protocol %q is not one of %v", ErrInvalidConfig, s.Protocol, securityProtocols)
+	}
+	if s.IsSASL() {
+		mechanism := strings.ToUpper(s.SASL.Mechanism)
+		if !slices.Contains(saslMechanisms, mechanism) {
+			return fmt.Errorf(
+				"%w: security.sasl.mechanism %q is not one of %v", ErrInvalidConfig, s.SASL.Mechanism, saslMechanisms,
+			)
+		}
+		if mechanism == "OAUTHBEARER" {
+			if s.SASL.TokenEndpointURL == "" || s.SASL.ClientID == "" || s.SASL.ClientSecret == "" {
+				return fmt.Errorf(
+					"%w: OAUTHBEARER requires tokenEndpointUrl, clientId and clientSecret", ErrInvalidConfig,
+				)
+			}
+		} else if s.SASL.Username == "" || s.SASL.Password == "" {
+			return fmt.Errorf("%w: %s requires username and password", ErrInvalidConfig, mechanism)
+		}
+	}
+
+	sr := &c.Kafka.SchemaRegistryAuth
+	if sr.Username != "" && sr.BearerToken != "" {
+		return fmt.Errorf("%w: schema registry basic and bearer auth are mutually exclusive", ErrInvalidConfig)
+	}
+	if (sr.Username == "") != (sr.Password == "") {
+		return fmt.Errorf("%w: schema registry basic auth requires username and password", ErrInvalidConfig)
+	}
+
+	files := map[string]string{
+		"security.ssl.caLocation":         s.SSL.CALocation,
+		"security.ssl.certLocation":       s.SSL.CertLocation,
+		"security.ssl.keyLocation":        s.SSL.KeyLocation,
+		"schemaRegistryAuth.caLocation":   sr.CALocation,
+		"schemaRegistryAuth.certLocation": sr.CertLocation,
+		"schemaRegistryAuth.keyLocation":  sr.KeyLocation,
+	}
+	for name, path := range files {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidConfig, name, err)
+		}
+	}
+	return nil
+}
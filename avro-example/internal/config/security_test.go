@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// secretFile writes secret to a file, as mounted kubernetes secrets end with a newline
+func secretFile(t *testing.T, secret string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret")
+	err := os.WriteFile(path, []byte(secret+"\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSecretFiles(t *testing.T) {
+	path := secretFile(t, "s3cret")
+	tests := []struct {
+		name   string
+		yaml   string
+		secret func(cfg *Config) string
+	}{
+		{
+			name: "sasl password",
+			yaml: `
+  security:
+    protocol: "SASL_SSL"
+    sasl:
+      mechanism: "SCRAM-SHA-512"
+      username: "user"
+      passwordFile: %q
+`,
+			secret: func(cfg *Config) string { return cfg.Kafka.Security.SASL.Password },
+		},
+		{
+			name: "oauthbearer client secret",
+			yaml: `
+  security:
+    protocol: "SASL_SSL"
+    sasl:
+      mechanism: "OAUTHBEARER"
+      tokenEndpointUrl: "https://auth.example.com/token"
+      clientId: "client"
+      clientSecretFile: %q
+`,
+			secret: func(cfg *Config) string { return cfg.Kafka.Security.SASL.ClientSecret },
+		},
+		{
+			name: "ssl key password",
+			yaml: `
+  security:
+    ssl:
+      keyPasswordFile: %q
+`,
+			secret: func(cfg *Config) string { return cfg.Kafka.Security.SSL.KeyPassword },
+		},
+		{
+			name: "schema registry password",
+			yaml: `
+  schemaRegistryAuth:
+    username: "user"
+    passwordFile: %q
+`,
+			secret: func(cfg *Config) string { return cfg.Kafka.SchemaRegistryAuth.Password },
+		},
+		{
+			name: "schema registry bearer token",
+			yaml: `
+  schemaRegistryAuth:
+    bearerTokenFile: %q
+`,
+			secret: func(cfg *Config) string { return cfg.Kafka.SchemaRegistryAuth.BearerToken },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := load(t, fmt.Sprintf(tt.yaml, path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.secret(cfg); got != "s3cret" {
+				t.Errorf("got secret %q, want %q", got, "s3cret")
+			}
+		})
+	}
+}
+
+func TestSecretFileInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{
+			name: "both value and file",
+			yaml: fmt.Sprintf(`
+  schemaRegistryAuth:
+    username: "user"
+    password: "s3cret"
+    passwordFile: %q
+`, secretFile(t, "s3cret")),
+		},
+		{
+			name: "missing file",
+			yaml: `
+  schemaRegistryAuth:
+    username: "user"
+    passwordFile: "/nonexistent/secret"
+`,
+		},
+		{
+			name: "sasl without password",
+			yaml: `
+  security:
+    protocol: "SASL_PLAINTEXT"
+    sasl:
+      mechanism: "PLAIN"
+      username: "user"
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.yaml)
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("got %v, want %v", err, ErrInvalidConfig)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package dto
+
+import (
+	"io"
+
+	"github.com/actgardner/gogen-avro/v10/vm/types"
+)
+
+// AvroRecord is satisfied by a pointer to any type generated by gogen-avro,
+// e.g. *User. It lets brokers work with T while (de)serializing through *T.
+type AvroRecord[T any] interface {
+	*T
+	types.Field
+	Serialize(w io.Writer) error
+	Schema() string
+	SchemaName() string
+}
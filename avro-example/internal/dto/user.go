@@ -1,4 +1,8 @@
 // Code generated by github.com/actgardner/gogen-avro/v10. DO NOT EDIT.
+/*
+ * SOURCE:
+ *     user.avsc
+ */
 package dto
 
 import (
@@ -73,7 +77,7 @@ func (r User) Serialize(w io.Writer) error {
 }
 
 func (r User) Schema() string {
-	return "{\"fields\":[{\"name\":\"name\",\"type\":\"string\"},{\"name\":\"favorite_number\",\"type\":\"long\"},{\"name\":\"favorite_color\",\"type\":\"string\"}],\"name\":\"kafkapracticum.User\",\"type\":\"record\"}"
+	return "{\"fields\":[{\"name\":\"name\",\"type\":\"string\",\"validation\":{\"notEmpty\":true}},{\"name\":\"favorite_number\",\"type\":\"long\"},{\"name\":\"favorite_color\",\"type\":\"string\"}],\"name\":\"kafkapracticum.User\",\"type\":\"record\"}"
 }
 
 func (r User) SchemaName() string {
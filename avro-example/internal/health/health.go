@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrKafkaUnreachable          = errors.New("kafka is unreachable")
+	ErrSchemaRegistryUnreachable = errors.New("schema registry is unreachable")
+)
+
+// DefaultCheckTimeout is used by Ready when ctx has no deadline
+var DefaultCheckTimeout = 2 * time.Second
+
+// Checker is implemented by kafka clients and applications exposing health checks
+type Checker interface {
+	// Live reports whether checked part is able to work at all
+	Live() error
+	// Ready reports whether checked part is able to serve now
+	Ready(ctx context.Context) error
+}
+
+// TimeoutMs returns time left till ctx deadline in milliseconds.
+// It's at least 1, kafka client treats negative timeout as infinite.
+func TimeoutMs(ctx context.Context) int {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return int(DefaultCheckTimeout.Milliseconds())
+	}
+	return max(int(time.Until(deadline).Milliseconds()), 1)
+}
+
+// SubjectLister is a part of schema registry client used to check it's reachable
+type SubjectLister interface {
+	GetAllSubjects() ([]string, error)
+}
+
+// CheckSchemaRegistry reports whether schema registry responds until ctx is done
+// or DefaultCheckTimeout passes if ctx has no deadline. Registry client doesn't
+// take ctx, so the request is left to finish by client request timeout.
+func CheckSchemaRegistry(ctx context.Context, registry SubjectLister) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultCheckTimeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := registry.GetAllSubjects()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrSchemaRegistryUnreachable, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %w", ErrSchemaRegistryUnreachable, ctx.Err())
+	}
+}
@@ -0,0 +1,68 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeoutMs(t *testing.T) {
+	if got, want := TimeoutMs(context.Background()), int(DefaultCheckTimeout.Milliseconds()); got != want {
+		t.Errorf("got %d without deadline, want %d", got, want)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if got := TimeoutMs(ctx); got <= 50000 || got > 60000 {
+		t.Errorf("got %d for a minute deadline", got)
+	}
+
+	// expired deadline must not turn into infinite timeout
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if got := TimeoutMs(ctx); got != 1 {
+		t.Errorf("got %d after deadline, want 1", got)
+	}
+}
+
+// registry lists subjects after delay or fails with err
+type registry struct {
+	delay time.Duration
+	err   error
+}
+
+func (r registry) GetAllSubjects() ([]string, error) {
+	time.Sleep(r.delay)
+	return nil, r.err
+}
+
+func TestCheckSchemaRegistry(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry registry
+		timeout  time.Duration
+		wantErr  error
+	}{
+		{"reachable", registry{}, time.Second, nil},
+		{"fails", registry{err: errors.New("connection refused")}, time.Second, ErrSchemaRegistryUnreachable},
+		{"doesn't respond in time", registry{delay: time.Minute}, 50 * time.Millisecond, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			start := time.Now()
+			err := CheckSchemaRegistry(ctx, tt.registry)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrSchemaRegistryUnreachable) {
+				t.Errorf("got %v, want %v", err, ErrSchemaRegistryUnreachable)
+			}
+			if elapsed := time.Since(start); elapsed > tt.timeout+time.Second {
+				t.Errorf("check took %s with %s timeout", elapsed, tt.timeout)
+			}
+		})
+	}
+}
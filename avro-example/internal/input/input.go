@@ -0,0 +1,255 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/avroschema"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+)
+
+var (
+	// ErrInvalidRecord relates to a single record only, reading may go on after it
+	ErrInvalidRecord = errors.New("invalid record")
+	ErrUnknownFormat = errors.New("unknown input format")
+	ErrUnknownColumn = errors.New("csv column doesn't match any record field")
+)
+
+// input formats
+const (
+	// FormatInteractive asks user for every field of record
+	FormatInteractive = "interactive"
+	// FormatJSONLines is one json object per line
+	FormatJSONLines = "jsonl"
+	// FormatCSV is csv with header, columns are matched with record fields by name
+	FormatCSV = "csv"
+	// FormatAvroJSON is a stream of records in avro json encoding
+	FormatAvroJSON = "avro-json"
+)
+
+var Formats = []string{FormatInteractive, FormatJSONLines, FormatCSV, FormatAvroJSON}
+
+// Source reads records one by one
+type Source[T any] interface {
+	// Next returns the next record or io.EOF when input is over
+	Next() (T, error)
+}
+
+// New returns source of records in the given format read from r.
+// Interactive source writes prompts to w. csvMapping maps csv columns
+// to record fields, columns missing in it are matched by their own name.
+func New[T any, PT dto.AvroRecord[T]](format string, r io.Reader, w io.Writer, csvMapping map[string]string) (Source[T], error) {
+	var zero T
+	fields, err := parseFields(PT(&zero).Schema())
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatInteractive:
+		return &prompt[T, PT]{reader: bufio.NewReader(r), w: w, fields: fields}, nil
+	case FormatJSONLines:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, maxLineSize)
+		return &jsonLines[T, PT]{scanner: scanner}, nil
+	case FormatCSV:
+		return newCSV[T, PT](r, fields, csvMapping)
+	case FormatAvroJSON:
+		decoder := json.NewDecoder(r)
+		decoder.UseNumber()
+		return &avroJSON[T, PT]{decoder: decoder, fields: fields}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+}
+
+// maxLineSize limits length of json line
+const maxLineSize = 1024 * 1024
+
+// invalid wraps error of record at the given position with ErrInvalidRecord
+func invalid(position string, n int, err error) error {
+	return fmt.Errorf("%w: %s %d: %w", ErrInvalidRecord, position, n, err)
+}
+
+// jsonLines reads records with their UnmarshalJSON (e.g. dto.User.UnmarshalJSON)
+type jsonLines[T any, PT dto.AvroRecord[T]] struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (s *jsonLines[T, PT]) Next() (T, error) {
+	var record T
+	for s.scanner.Scan() {
+		s.line++
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		err := json.Unmarshal(line, PT(&record))
+		if err != nil {
+			return record, invalid("line", s.line, err)
+		}
+		return record, nil
+	}
+	err := s.scanner.Err()
+	if err != nil {
+		return record, err
+	}
+	return record, io.EOF
+}
+
+// csvSource reads csv with header
+type csvSource[T any, PT dto.AvroRecord[T]] struct {
+	reader *csv.Reader
+	fields []avroschema.Field
+	// columns holds index of record field for every csv column
+	columns []int
+}
+
+func newCSV[T any, PT dto.AvroRecord[T]](r io.Reader, fields []avroschema.Field, mapping map[string]string) (*csvSource[T, PT], error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv header: %w", err)
+	}
+	columns := make([]int, 0, len(header))
+	for _, column := range header {
+		name := strings.TrimSpace(column)
+		if mapped, ok := mapping[name]; ok {
+			name = mapped
+		}
+		index := -1
+		for i, f := range fields {
+			if f.Name == name {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+		}
+		columns = append(columns, index)
+	}
+	return &csvSource[T, PT]{reader: reader, fields: fields, columns: columns}, nil
+}
+
+func (s *csvSource[T, PT]) Next() (T, error) {
+	var record T
+	row, err := s.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return record, invalid("line", parseErr.Line, parseErr.Err)
+		}
+		return record, err
+	}
+	line, _ := s.reader.FieldPos(0)
+	values := make(map[string]any, len(row))
+	for column, text := range row {
+		f := s.fields[s.columns[column]]
+		value, err := parseText(f, text)
+		if err != nil {
+			return record, invalid("line", line, fmt.Errorf("field %s: %w", f.Name, err))
+		}
+		values[f.Name] = value
+	}
+	err = setFields(PT(&record), s.fields, values)
+	if err != nil {
+		return record, invalid("line", line, err)
+	}
+	return record, nil
+}
+
+// avroJSON reads records in avro json encoding,
+// see https://avro.apache.org/docs/1.11.1/specification/#json-encoding
+type avroJSON[T any, PT dto.AvroRecord[T]] struct {
+	decoder *json.Decoder
+	fields  []avroschema.Field
+	n       int
+}
+
+func (s *avroJSON[T, PT]) Next() (T, error) {
+	var record T
+	var values map[string]any
+	err := s.decoder.Decode(&values)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			s.n++
+			return record, invalid("record", s.n, err)
+		}
+		// syntax error breaks the stream, so reading can't go on
+		return record, err
+	}
+	s.n++
+	err = setFields(PT(&record), s.fields, values)
+	if err != nil {
+		return record, invalid("record", s.n, err)
+	}
+	return record, nil
+}
+
+// prompt asks user for send or exit command and then for every field of record
+type prompt[T any, PT dto.AvroRecord[T]] struct {
+	reader *bufio.Reader
+	w      io.Writer
+	fields []avroschema.Field
+	n      int
+}
+
+func (s *prompt[T, PT]) Next() (T, error) {
+	var record T
+	for {
+		fmt.Fprint(s.w, "Command: ")
+		command, err := s.readLine()
+		if err != nil {
+			return record, err
+		}
+		if command == "exit" {
+			return record, io.EOF
+		}
+		if command == "send" {
+			break
+		}
+		fmt.Fprintln(s.w, "Введите команду exit для выхода или send для отправки сообщений")
+	}
+
+	s.n++
+	// all fields are read before parsing, so invalid value doesn't shift the next prompts
+	texts := make([]string, 0, len(s.fields))
+	for _, f := range s.fields {
+		fmt.Fprintf(s.w, "Enter %s: ", strings.ReplaceAll(f.Name, "_", " "))
+		text, err := s.readLine()
+		if err != nil {
+			return record, err
+		}
+		texts = append(texts, text)
+	}
+	values := make(map[string]any, len(s.fields))
+	for i, f := range s.fields {
+		value, err := parseText(f, texts[i])
+		if err != nil {
+			return record, invalid("record", s.n, fmt.Errorf("field %s: %w", f.Name, err))
+		}
+		values[f.Name] = value
+	}
+	err := setFields(PT(&record), s.fields, values)
+	if err != nil {
+		return record, invalid("record", s.n, err)
+	}
+	return record, nil
+}
+
+// readLine returns the next line without surrounding spaces
+func (s *prompt[T, PT]) readLine() (string, error) {
+	line, err := s.reader.ReadString('\n')
+	if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,169 @@
+package input
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/avroschema"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/actgardner/gogen-avro/v10/vm/types"
+)
+
+// result is a record or error returned by Source.Next
+type result struct {
+	user dto.User
+	err  error
+}
+
+func TestSources(t *testing.T) {
+	alice := dto.User{Name: "Alice", Favorite_number: 7, Favorite_color: "red"}
+	bob := dto.User{Name: "Bob", Favorite_number: -1, Favorite_color: "blue"}
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		mapping map[string]string
+		want    []result
+	}{
+		{
+			name:   "jsonl",
+			format: FormatJSONLines,
+			input: `{"name": "Alice", "favorite_number": 7, "favorite_color": "red"}
+
+{"name": "Bob", "favorite_number": -1, "favorite_color": "blue"}
+`,
+			want: []result{{user: alice}, {user: bob}},
+		},
+		{
+			name:   "jsonl bad records",
+			format: FormatJSONLines,
+			input: `{"name": "Alice", "favorite_number": 7, "favorite_color": "red"}
+{"name": "Bob",
+{"name": "Bob", "favorite_number": "many", "favorite_color": "blue"}
+{"name": "Bob", "favorite_number": -1, "favorite_color": "blue"}
+`,
+			want: []result{{user: alice}, {err: ErrInvalidRecord}, {err: ErrInvalidRecord}, {user: bob}},
+		},
+		{
+			name:   "csv",
+			format: FormatCSV,
+			input: `favorite_color, name, favorite_number
+red, Alice, 7
+blue, Bob, -1
+`,
+			want: []result{{user: alice}, {user: bob}},
+		},
+		{
+			name:    "csv mapping",
+			format:  FormatCSV,
+			input:   "user_name,color,favorite_number\nAlice,red,7\n",
+			mapping: map[string]string{"user_name": "name", "color": "favorite_color"},
+			want:    []result{{user: alice}},
+		},
+		{
+			name:   "csv bad records",
+			format: FormatCSV,
+			input: `name,favorite_number,favorite_color
+Alice,seven,red
+Alice,7
+Alice,9223372036854775808,red
+Bob,-1,blue
+`,
+			want: []result{{err: ErrInvalidRecord}, {err: ErrInvalidRecord}, {err: ErrInvalidRecord}, {user: bob}},
+		},
+		{
+			name:   "avro-json",
+			format: FormatAvroJSON,
+			input: `{"name": "Alice", "favorite_number": 7, "favorite_color": "red"}
+{"name": "Bob", "favorite_number": -1, "favorite_color": "blue"}`,
+			want: []result{{user: alice}, {user: bob}},
+		},
+		{
+			name:   "avro-json bad records",
+			format: FormatAvroJSON,
+			input: `{"name": "Alice", "favorite_number": 7}
+{"name": "Alice", "favorite_number": 7.5, "favorite_color": "red"}
+{"name": 1, "favorite_number": 7, "favorite_color": "red"}
+{"name": "Bob", "favorite_number": -1, "favorite_color": "blue"}`,
+			want: []result{{err: ErrInvalidRecord}, {err: ErrInvalidRecord}, {err: ErrInvalidRecord}, {user: bob}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := New[dto.User](tt.format, strings.NewReader(tt.input), io.Discard, tt.mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range tt.want {
+				user, err := source.Next()
+				if !errors.Is(err, want.err) {
+					t.Fatalf("record %d: got error %v, want %v", i, err, want.err)
+				}
+				if err == nil && user != want.user {
+					t.Errorf("record %d: got %+v, want %+v", i, user, want.user)
+				}
+			}
+			_, err = source.Next()
+			if !errors.Is(err, io.EOF) {
+				t.Errorf("got %v at the end of input, want %v", err, io.EOF)
+			}
+		})
+	}
+}
+
+func TestNewUnknown(t *testing.T) {
+	_, err := New[dto.User]("xml", strings.NewReader(""), io.Discard, nil)
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("got %v, want %v", err, ErrUnknownFormat)
+	}
+	_, err = New[dto.User](FormatCSV, strings.NewReader("name,age\n"), io.Discard, nil)
+	if !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("got %v, want %v", err, ErrUnknownColumn)
+	}
+}
+
+// field records the value set by setPrimitive
+type field struct {
+	types.Field
+	value any
+}
+
+func (f *field) SetInt(v int32)     { f.value = v }
+func (f *field) SetLong(v int64)    { f.value = v }
+func (f *field) SetFloat(v float32) { f.value = v }
+
+func TestSetPrimitiveRange(t *testing.T) {
+	tests := []struct {
+		typ   string
+		text  string
+		want  any
+		valid bool
+	}{
+		{"int", "2147483647", int32(2147483647), true},
+		{"int", "-2147483648", int32(-2147483648), true},
+		{"int", "2147483648", nil, false},
+		{"int", "-2147483649", nil, false},
+		{"long", "2147483648", int64(2147483648), true},
+		{"long", "9223372036854775808", nil, false},
+		{"float", "1.5", float32(1.5), true},
+		{"float", "1e39", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ+" "+tt.text, func(t *testing.T) {
+			value, err := parseText(avroschema.Field{Name: "n", Type: tt.typ}, tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var target field
+			err = setPrimitive(&target, tt.typ, value)
+			if tt.valid != (err == nil) {
+				t.Fatalf("got error %v, want valid %v", err, tt.valid)
+			}
+			if target.value != tt.want {
+				t.Errorf("got %v, want %v", target.value, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package input
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/avroschema"
+	"github.com/actgardner/gogen-avro/v10/vm/types"
+)
+
+// ErrUnsupportedType means record has field of type input formats can't set
+var ErrUnsupportedType = avroschema.ErrUnsupportedType
+
+// parseFields returns fields of avro record schema, only primitive
+// types and unions of them are supported
+func parseFields(schema string) ([]avroschema.Field, error) {
+	fields, err := avroschema.Parse(schema)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		types := f.Union
+		if f.Union == nil {
+			types = []string{f.Type}
+		}
+		for _, typ := range types {
+			err := checkPrimitive(typ)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+		}
+	}
+	return fields, nil
+}
+
+func checkPrimitive(name string) error {
+	switch name {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedType, name)
+}
+
+// setFields sets fields of record from avro json values, missing fields get default values
+func setFields(record types.Field, fields []avroschema.Field, values map[string]any) error {
+	for i, f := range fields {
+		value, ok := values[f.Name]
+		if !ok {
+			if !f.HasDefault {
+				return fmt.Errorf("no value specified for %s", f.Name)
+			}
+			record.SetDefault(i)
+			continue
+		}
+		err := setField(record, i, f, value)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// setField sets field i of record from avro json value,
+// union values are either null or {"<type>": value}
+func setField(record types.Field, i int, f avroschema.Field, value any) error {
+	if f.Union == nil {
+		return setPrimitive(record.Get(i), f.Type, value)
+	}
+	if value == nil {
+		if !f.Nullable() {
+			return errors.New("null isn't allowed")
+		}
+		record.NullField(i)
+		return nil
+	}
+	wrapped, ok := value.(map[string]any)
+	if !ok || len(wrapped) != 1 {
+		return fmt.Errorf("union value must be null or {\"<type>\": value}, got %v", value)
+	}
+	for memberIndex, member := range f.Union {
+		memberValue, ok := wrapped[member]
+		if !ok {
+			continue
+		}
+		union := record.Get(i)
+		union.SetLong(int64(memberIndex))
+		err := setPrimitive(union.Get(memberIndex), member, memberValue)
+		if err != nil {
+			return err
+		}
+		union.Finalize()
+		return nil
+	}
+	return fmt.Errorf("union has none of types %v", f.Union)
+}
+
+// setPrimitive sets target from avro json value of primitive type typ
+func setPrimitive(target types.Field, typ string, value any) error {
+	switch typ {
+	case "boolean":
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("%v is not boolean", value)
+		}
+		target.SetBoolean(v)
+	case "int", "long":
+		n, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%v is not a number", value)
+		}
+		v, err := n.Int64()
+		if err != nil {
+			return fmt.Errorf("%v is not an integer", value)
+		}
+		if typ == "int" {
+			if v < math.MinInt32 || v > math.MaxInt32 {
+				return fmt.Errorf("%v is out of int range", value)
+			}
+			target.SetInt(int32(v))
+		} else {
+			target.SetLong(v)
+		}
+	case "float", "double":
+		n, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%v is not a number", value)
+		}
+		v, err := n.Float64()
+		if err != nil {
+			return err
+		}
+		if typ == "float" {
+			if math.Abs(v) > math.MaxFloat32 {
+				return fmt.Errorf("%v is out of float range", value)
+			}
+			target.SetFloat(float32(v))
+		} else {
+			target.SetDouble(v)
+		}
+	case "string":
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v is not a string", value)
+		}
+		target.SetString(v)
+	case "bytes":
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v is not a string", value)
+		}
+		// avro json encodes bytes as string of code points 0-255
+		b := make([]byte, 0, len(v))
+		for _, r := range v {
+			if r > 255 {
+				return fmt.Errorf("%q is not a byte string", v)
+			}
+			b = append(b, byte(r))
+		}
+		target.SetBytes(b)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, typ)
+	}
+	return nil
+}
+
+// parseText turns text value (e.g. csv cell) into avro json value of field,
+// empty text is null for nullable fields
+func parseText(f avroschema.Field, text string) (any, error) {
+	if f.Union == nil {
+		return parsePrimitive(f.Type, text)
+	}
+	if text == "" {
+		return nil, nil
+	}
+	var errs []error
+	for _, member := range f.Union {
+		if member == "null" {
+			continue
+		}
+		value, err := parsePrimitive(member, text)
+		if err == nil {
+			return map[string]any{member: value}, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// parsePrimitive turns text into avro json value of primitive type typ
+func parsePrimitive(typ string, text string) (any, error) {
+	switch typ {
+	case "boolean":
+		return strconv.ParseBool(strings.TrimSpace(text))
+	case "int", "long", "float", "double":
+		text = strings.TrimSpace(text)
+		_, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", text)
+		}
+		return json.Number(text), nil
+	case "bytes":
+		// bytes are base64 encoded in text formats
+		b, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, err
+		}
+		runes := make([]rune, 0, len(b))
+		for _, c := range b {
+			runes = append(runes, rune(c))
+		}
+		return string(runes), nil
+	case "null":
+		if text != "" {
+			return nil, fmt.Errorf("%q is not null", text)
+		}
+		return nil, nil
+	default:
+		return text, nil
+	}
+}
@@ -1,8 +1,8 @@
 package logger
 
 import (
+	"io"
 	"log/slog"
-	"os"
 )
 
 const (
@@ -11,15 +11,15 @@ const (
 	envProd  = "prod"
 )
 
-// New creates logger with predefine setting (depends on environment).
-func New(env string) *slog.Logger {
+// New creates logger writing to w with predefine setting (depends on environment).
+func New(env string, w io.Writer) *slog.Logger {
 	var log *slog.Logger
 
 	switch env {
 	case envLocal:
 		log = slog.New(
 			slog.NewTextHandler(
-				os.Stdout, &slog.HandlerOptions{
+				w, &slog.HandlerOptions{
 					Level:     slog.LevelInfo,
 					AddSource: true,
 				},
@@ -28,7 +28,7 @@ func New(env string) *slog.Logger {
 	case envDemo:
 		log = slog.New(
 			slog.NewJSONHandler(
-				os.Stdout, &slog.HandlerOptions{
+				w, &slog.HandlerOptions{
 					Level:     slog.LevelDebug,
 					AddSource: true,
 				},
@@ -37,7 +37,7 @@ func New(env string) *slog.Logger {
 	case envProd:
 		log = slog.New(
 			slog.NewJSONHandler(
-				os.Stdout, &slog.HandlerOptions{
+				w, &slog.HandlerOptions{
 					Level:     slog.LevelInfo,
 					AddSource: true,
 				},
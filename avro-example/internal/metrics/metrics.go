@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds all application metrics. Metrics are package level,
+// because several brokers (e.g. retry consumers) share them.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+// producer metrics
+var (
+	MessagesSent = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_producer_messages_sent_total",
+		Help: "Number of messages successfully delivered to kafka.",
+	}, []string{"topic"})
+	DeliveryFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_producer_delivery_failures_total",
+		Help: "Number of messages failed to be delivered to kafka by error code.",
+	}, []string{"topic", "code"})
+	SerializationDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_producer_serialization_duration_seconds",
+		Help:    "Time of message serialization including schema registry lookup.",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8),
+	}, []string{"topic"})
+	DeliveryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_producer_delivery_duration_seconds",
+		Help:    "Time from producing message to receiving its delivery report.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"topic"})
+	ValidationFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_producer_validation_failures_total",
+		Help: "Number of records rejected by validation by invalid field.",
+	}, []string{"topic", "field"})
+	ProducerQueueMessages = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "kafka_producer_queue_messages",
+		Help: "Number of messages waiting in producer queues.",
+	})
+)
+
+// gateway metrics
+var (
+	GatewayRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_requests_total",
+		Help: "Number of http requests to gateway by topic and response status code.",
+	}, []string{"topic", "code"})
+	GatewayRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_request_duration_seconds",
+		Help:    "Time of handling http request to gateway including delivery.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"topic"})
+)
+
+// consumer metrics
+var (
+	MessagesConsumed = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_messages_consumed_total",
+		Help: "Number of messages consumed from kafka.",
+	}, []string{"topic"})
+	DeserializationFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_deserialization_failures_total",
+		Help: "Number of messages which could not be deserialized.",
+	}, []string{"topic"})
+	HandlerDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_consumer_handler_duration_seconds",
+		Help:    "Time of message handling by result.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"topic", "result"})
+	BatchSize = factory.NewHistogram(prometheus.HistogramOpts{
+		Name:    "kafka_consumer_batch_size_messages",
+		Help:    "Number of records passed to batch handler.",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+	})
+	BatchDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_consumer_batch_duration_seconds",
+		Help:    "Time of batch handling by result.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"result"})
+	CommitFailures = factory.NewCounter(prometheus.CounterOpts{
+		Name: "kafka_consumer_commit_failures_total",
+		Help: "Number of failed offset commits.",
+	})
+	Rebalances = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_rebalances_total",
+		Help: "Number of rebalance events by consumer group and event (assigned, revoked or lost).",
+	}, []string{"group", "event"})
+	AssignedPartitions = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_assigned_partitions",
+		Help: "Number of partitions assigned to consumer by consumer group.",
+	}, []string{"group"})
+	ConsumerLag = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag_messages",
+		Help: "Consumer lag per partition reported by librdkafka statistics.",
+	}, []string{"group", "topic", "partition"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler returns http handler exposing metrics in prometheus format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// stats is a part of librdkafka statistics,
+// see https://github.com/confluentinc/librdkafka/blob/master/STATISTICS.md
+type stats struct {
+	MsgCnt int64 `json:"msg_cnt"`
+	Topics map[string]struct {
+		Partitions map[string]struct {
+			Partition   int32 `json:"partition"`
+			ConsumerLag int64 `json:"consumer_lag"`
+		} `json:"partitions"`
+	} `json:"topics"`
+}
+
+// ObserveProducerStats sets producer metrics from librdkafka statistics json
+func ObserveProducerStats(statsJSON string) error {
+	var s stats
+	err := json.Unmarshal([]byte(statsJSON), &s)
+	if err != nil {
+		return err
+	}
+	ProducerQueueMessages.Set(float64(s.MsgCnt))
+	return nil
+}
+
+// ObserveConsumerStats sets consumer lag of group from librdkafka statistics json
+func ObserveConsumerStats(group, statsJSON string) error {
+	var s stats
+	err := json.Unmarshal([]byte(statsJSON), &s)
+	if err != nil {
+		return err
+	}
+	for topic, t := range s.Topics {
+		for _, p := range t.Partitions {
+			// -1 is internal UA/UnAssigned partition,
+			// lag is -1 while it is unknown (e.g. partition isn't assigned)
+			if p.Partition < 0 || p.ConsumerLag < 0 {
+				continue
+			}
+			ConsumerLag.WithLabelValues(group, topic, strconv.Itoa(int(p.Partition))).Set(float64(p.ConsumerLag))
+		}
+	}
+	return nil
+}
+
+// DeleteConsumerLag removes lag of partition, which isn't assigned to group anymore,
+// otherwise its last value is exported until the process exits
+func DeleteConsumerLag(group, topic string, partition int32) {
+	ConsumerLag.DeleteLabelValues(group, topic, strconv.Itoa(int(partition)))
+}
@@ -0,0 +1,369 @@
+package output
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/avroschema"
+	consumer "github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/consumer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown output format")
+	ErrUnknownColumn = errors.New("unknown metadata column")
+)
+
+// output formats
+const (
+	// FormatLog logs records, it isn't a sink
+	FormatLog = "log"
+	// FormatJSONLines is one json object per line made by MarshalJSON of record (e.g. dto.User.MarshalJSON)
+	FormatJSONLines = "jsonl"
+	// FormatAvroJSON is avro json encoding of record, union values are {"<type>": value}
+	FormatAvroJSON = "avro-json"
+	// FormatCSV is csv with header
+	FormatCSV = "csv"
+	// FormatTable is a table with fixed column width
+	FormatTable = "table"
+	// FormatAvro is avro binary encoding of record prefixed with its length
+	// as 4 bytes big endian, schema registry framing is stripped
+	FormatAvro = "avro"
+)
+
+var Formats = []string{FormatLog, FormatJSONLines, FormatAvroJSON, FormatCSV, FormatTable, FormatAvro}
+
+// metadata columns
+const (
+	ColumnTopic     = "topic"
+	ColumnPartition = "partition"
+	ColumnOffset    = "offset"
+	ColumnTimestamp = "timestamp"
+	ColumnKey       = "key"
+	ColumnHeaders   = "headers"
+)
+
+var Columns = []string{ColumnTopic, ColumnPartition, ColumnOffset, ColumnTimestamp, ColumnKey, ColumnHeaders}
+
+// Sink writes consumed records, it is safe for concurrent use
+type Sink[T any] interface {
+	Write(msg consumer.Record[T]) error
+	// Close flushes buffered output, it doesn't close the underlying writer
+	Close() error
+}
+
+// New returns sink writing records in the given format to w.
+// columns are metadata columns written before record fields,
+// avro format has no place for them, so they must be empty.
+func New[T any, PT dto.AvroRecord[T]](format string, w io.Writer, columns []string) (Sink[T], error) {
+	for _, column := range columns {
+		if !slices.Contains(Columns, column) {
+			return nil, fmt.Errorf("%w: %s is not one of %v", ErrUnknownColumn, column, Columns)
+		}
+	}
+	var zero T
+	fields, err := avroschema.Parse(PT(&zero).Schema())
+	if err != nil {
+		return nil, err
+	}
+	s := &sink[T, PT]{w: w, columns: columns, fields: fields}
+	switch format {
+	case FormatJSONLines:
+		s.write = s.writeJSON(false)
+	case FormatAvroJSON:
+		s.write = s.writeJSON(true)
+	case FormatCSV:
+		s.csv = csv.NewWriter(w)
+		s.write = s.writeCSV
+	case FormatTable:
+		s.write = s.writeTable
+	case FormatAvro:
+		if len(columns) > 0 {
+			return nil, fmt.Errorf("%w: avro format has no metadata columns", ErrUnknownColumn)
+		}
+		s.write = s.writeAvro
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+	return s, nil
+}
+
+type sink[T any, PT dto.AvroRecord[T]] struct {
+	mu      sync.Mutex
+	w       io.Writer
+	columns []string
+	fields  []avroschema.Field
+	write   func(msg consumer.Record[T]) error
+	// csv is set in csv format
+	csv *csv.Writer
+	// header is written before the first record of csv and table
+	headerWritten bool
+}
+
+func (s *sink[T, PT]) Write(msg consumer.Record[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(msg)
+}
+
+func (s *sink[T, PT]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.csv != nil {
+		s.csv.Flush()
+		return s.csv.Error()
+	}
+	return nil
+}
+
+// metadata returns value of metadata column
+func metadata[T any](msg consumer.Record[T], column string) any {
+	switch column {
+	case ColumnTopic:
+		return msg.Topic
+	case ColumnPartition:
+		return msg.Partition
+	case ColumnOffset:
+		return int64(msg.Offset)
+	case ColumnTimestamp:
+		return msg.Timestamp.Format(time.RFC3339Nano)
+	case ColumnKey:
+		if msg.Key == nil {
+			return nil
+		}
+		return string(msg.Key)
+	case ColumnHeaders:
+		headers := make(map[string]string, len(msg.Headers))
+		for _, h := range msg.Headers {
+			headers[h.Key] = string(h.Value)
+		}
+		return headers
+	}
+	return nil
+}
+
+// values returns record fields by name as MarshalJSON encodes them
+func (s *sink[T, PT]) values(msg consumer.Record[T]) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(PT(&msg.Value))
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]json.RawMessage
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// unwrap returns value of union member, MarshalJSON encodes unions as {"<type>": value}
+func unwrap(raw json.RawMessage) json.RawMessage {
+	var wrapped map[string]json.RawMessage
+	if json.Unmarshal(raw, &wrapped) != nil || len(wrapped) != 1 {
+		return raw
+	}
+	for _, member := range wrapped {
+		return member
+	}
+	return raw
+}
+
+// writeJSON returns function writing record as json line, record fields are
+// in the "value" object when metadata columns are selected. Union values are
+// unwrapped unless avro json encoding is required.
+func (s *sink[T, PT]) writeJSON(avroJSON bool) func(msg consumer.Record[T]) error {
+	return func(msg consumer.Record[T]) error {
+		values, err := s.values(msg)
+		if err != nil {
+			return err
+		}
+		var value bytes.Buffer
+		value.WriteByte('{')
+		for i, f := range s.fields {
+			if i > 0 {
+				value.WriteByte(',')
+			}
+			raw := values[f.Name]
+			if f.Union != nil && !avroJSON {
+				raw = unwrap(raw)
+			}
+			writeMember(&value, f.Name, raw)
+		}
+		value.WriteByte('}')
+		if len(s.columns) == 0 {
+			value.WriteByte('\n')
+			_, err = s.w.Write(value.Bytes())
+			return err
+		}
+
+		var line bytes.Buffer
+		line.WriteByte('{')
+		for _, column := range s.columns {
+			raw, err := json.Marshal(metadata(msg, column))
+			if err != nil {
+				return err
+			}
+			writeMember(&line, column, raw)
+			line.WriteByte(',')
+		}
+		writeMember(&line, "value", value.Bytes())
+		line.WriteString("}\n")
+		_, err = s.w.Write(line.Bytes())
+		return err
+	}
+}
+
+// writeMember writes "name":raw to json object
+func writeMember(buf *bytes.Buffer, name string, raw json.RawMessage) {
+	key, _ := json.Marshal(name)
+	buf.Write(key)
+	buf.WriteByte(':')
+	if raw == nil {
+		raw = json.RawMessage("null")
+	}
+	buf.Write(raw)
+}
+
+// header returns names of metadata columns and record fields
+func (s *sink[T, PT]) header() []string {
+	header := slices.Clone(s.columns)
+	for _, f := range s.fields {
+		header = append(header, f.Name)
+	}
+	return header
+}
+
+// cells returns text of metadata columns and record fields
+func (s *sink[T, PT]) cells(msg consumer.Record[T]) ([]string, error) {
+	values, err := s.values(msg)
+	if err != nil {
+		return nil, err
+	}
+	cells := make([]string, 0, len(s.columns)+len(s.fields))
+	for _, column := range s.columns {
+		value := metadata(msg, column)
+		switch v := value.(type) {
+		case nil:
+			cells = append(cells, "")
+		case map[string]string:
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			cells = append(cells, string(raw))
+		default:
+			cells = append(cells, fmt.Sprint(v))
+		}
+	}
+	for _, f := range s.fields {
+		cells = append(cells, text(unwrap(values[f.Name])))
+	}
+	return cells, nil
+}
+
+// text returns json value as text: strings unquoted, null empty,
+// objects and arrays as compact json
+func text(raw json.RawMessage) string {
+	if raw == nil || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var compact bytes.Buffer
+	if json.Compact(&compact, raw) != nil {
+		return string(raw)
+	}
+	return compact.String()
+}
+
+func (s *sink[T, PT]) writeCSV(msg consumer.Record[T]) error {
+	if !s.headerWritten {
+		err := s.csv.Write(s.header())
+		if err != nil {
+			return err
+		}
+		s.headerWritten = true
+	}
+	cells, err := s.cells(msg)
+	if err != nil {
+		return err
+	}
+	err = s.csv.Write(cells)
+	if err != nil {
+		return err
+	}
+	// records are flushed one by one, so output can be piped
+	s.csv.Flush()
+	return s.csv.Error()
+}
+
+// tableColumnWidth is the minimal width of table column, longer values are truncated
+const tableColumnWidth = 16
+
+// writeTable writes record as table row, column width is fixed,
+// so rows are aligned while they are streamed
+func (s *sink[T, PT]) writeTable(msg consumer.Record[T]) error {
+	header := s.header()
+	var buf bytes.Buffer
+	if !s.headerWritten {
+		writeRow(&buf, header, header)
+		separators := make([]string, len(header))
+		for i, name := range header {
+			separators[i] = strings.Repeat("-", max(utf8.RuneCountInString(name), tableColumnWidth))
+		}
+		writeRow(&buf, header, separators)
+		s.headerWritten = true
+	}
+	cells, err := s.cells(msg)
+	if err != nil {
+		return err
+	}
+	writeRow(&buf, header, cells)
+	_, err = s.w.Write(buf.Bytes())
+	return err
+}
+
+// writeRow writes cells padded or truncated to width of their columns
+func writeRow(buf *bytes.Buffer, header, cells []string) {
+	for i, cell := range cells {
+		width := max(utf8.RuneCountInString(header[i]), tableColumnWidth)
+		cell = strings.NewReplacer("\n", " ", "\t", " ").Replace(cell)
+		if n := utf8.RuneCountInString(cell); n > width {
+			cell = string([]rune(cell)[:width-1]) + "…"
+		}
+		if i > 0 {
+			buf.WriteString(" | ")
+		}
+		buf.WriteString(cell)
+		if i < len(cells)-1 {
+			buf.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(cell)))
+		}
+	}
+	buf.WriteByte('\n')
+}
+
+// writeAvro writes avro binary encoding of record prefixed with its length
+func (s *sink[T, PT]) writeAvro(msg consumer.Record[T]) error {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4))
+	err := PT(&msg.Value).Serialize(&buf)
+	if err != nil {
+		return err
+	}
+	frame := buf.Bytes()
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+	_, err = s.w.Write(frame)
+	return err
+}
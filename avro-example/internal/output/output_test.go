@@ -0,0 +1,195 @@
+package output
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	consumer "github.com/AlexBlackNn/kafka-avro/avro-example/internal/broker/consumer"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// records are written by every sink in tests, the second one has no key and headers
+var records = []consumer.Record[dto.User]{
+	{
+		Key:       []byte("k1"),
+		Headers:   []kafka.Header{{Key: "h", Value: []byte("v")}},
+		Topic:     "users",
+		Partition: 1,
+		Offset:    42,
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Value:     dto.User{Name: "Alice", Favorite_number: 7, Favorite_color: "red"},
+	},
+	{
+		Topic:     "users",
+		Offset:    43,
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC),
+		Value:     dto.User{Name: `Bob, "the" Builder of everything`, Favorite_number: -1},
+	},
+}
+
+func TestSinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		columns []string
+		want    string
+	}{
+		{
+			name:   "jsonl",
+			format: FormatJSONLines,
+			want: `{"name":"Alice","favorite_number":7,"favorite_color":"red"}
+{"name":"Bob, \"the\" Builder of everything","favorite_number":-1,"favorite_color":""}
+`,
+		},
+		{
+			name:    "jsonl metadata",
+			format:  FormatJSONLines,
+			columns: Columns,
+			want: `{"topic":"users","partition":1,"offset":42,"timestamp":"2024-01-02T03:04:05Z","key":"k1","headers":{"h":"v"},"value":{"name":"Alice","favorite_number":7,"favorite_color":"red"}}
+{"topic":"users","partition":0,"offset":43,"timestamp":"2024-01-02T03:04:06Z","key":null,"headers":{},"value":{"name":"Bob, \"the\" Builder of everything","favorite_number":-1,"favorite_color":""}}
+`,
+		},
+		{
+			name:    "avro-json metadata",
+			format:  FormatAvroJSON,
+			columns: []string{ColumnOffset},
+			want: `{"offset":42,"value":{"name":"Alice","favorite_number":7,"favorite_color":"red"}}
+{"offset":43,"value":{"name":"Bob, \"the\" Builder of everything","favorite_number":-1,"favorite_color":""}}
+`,
+		},
+		{
+			name:   "csv",
+			format: FormatCSV,
+			want: `name,favorite_number,favorite_color
+Alice,7,red
+"Bob, ""the"" Builder of everything",-1,
+`,
+		},
+		{
+			name:    "csv metadata",
+			format:  FormatCSV,
+			columns: []string{ColumnTimestamp, ColumnKey, ColumnHeaders},
+			want: `timestamp,key,headers,name,favorite_number,favorite_color
+2024-01-02T03:04:05Z,k1,"{""h"":""v""}",Alice,7,red
+2024-01-02T03:04:06Z,,{},"Bob, ""the"" Builder of everything",-1,
+`,
+		},
+		{
+			name:   "table",
+			format: FormatTable,
+			want: "name             | favorite_number  | favorite_color\n" +
+				"---------------- | ---------------- | ----------------\n" +
+				"Alice            | 7                | red\n" +
+				"Bob, \"the\" Buil… | -1               | \n",
+		},
+		{
+			name:    "table metadata",
+			format:  FormatTable,
+			columns: []string{ColumnTopic, ColumnPartition, ColumnTimestamp},
+			want: "topic            | partition        | timestamp        | name             | favorite_number  | favorite_color\n" +
+				"---------------- | ---------------- | ---------------- | ---------------- | ---------------- | ----------------\n" +
+				"users            | 1                | 2024-01-02T03:0… | Alice            | 7                | red\n" +
+				"users            | 0                | 2024-01-02T03:0… | Bob, \"the\" Buil… | -1               | \n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			sink, err := New[dto.User](tt.format, &out, tt.columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, record := range records {
+				err := sink.Write(record)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			err = sink.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestAvroSink(t *testing.T) {
+	var out bytes.Buffer
+	sink, err := New[dto.User](FormatAvro, &out, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		err := sink.Write(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, record := range records {
+		var size uint32
+		err := binary.Read(&out, binary.BigEndian, &size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frame := io.LimitReader(&out, int64(size))
+		user, err := dto.DeserializeUser(frame)
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if user != record.Value {
+			t.Errorf("record %d: got %+v, want %+v", i, user, record.Value)
+		}
+	}
+	if out.Len() != 0 {
+		t.Errorf("%d bytes left after records", out.Len())
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		columns []string
+		err     error
+	}{
+		{"unknown format", "xml", nil, ErrUnknownFormat},
+		{"unknown column", FormatCSV, []string{"value"}, ErrUnknownColumn},
+		{"avro metadata", FormatAvro, []string{ColumnKey}, ErrUnknownColumn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New[dto.User](tt.format, io.Discard, tt.columns)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{`"text"`, "text"},
+		{`null`, ""},
+		{`7`, "7"},
+		{`{"string": "union"}`, "union"},
+		{`{"a": [1, 2]}`, `[1,2]`},
+		{`{"a": 1, "b": 2}`, `{"a":1,"b":2}`},
+	}
+	for _, tt := range tests {
+		if got := text(unwrap(json.RawMessage(tt.raw))); got != tt.want {
+			t.Errorf("got %q for %s, want %q", got, tt.raw, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	exporterNone   = "none"
+	exporterStdout = "stdout"
+	exporterOTLP   = "otlp"
+)
+
+var ErrUnknownExporter = errors.New("unknown trace exporter")
+
+// New configures global tracer provider and W3C trace context and baggage
+// propagators, stdout exporter writes spans to out. Returned function
+// flushes and stops the provider.
+func New(ctx context.Context, cfg config.TracingConfig, out io.Writer) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(
+		propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	)
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case exporterNone:
+		// spans are not recorded, but trace context is still propagated
+		return func(context.Context) error { return nil }, nil
+	case exporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+	case exporterOTLP:
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	default:
+		return nil, ErrUnknownExporter
+	}
+	if err != nil {
+		return nil, err
+	}
+	provider := NewProvider(cfg.ServiceName, exporter)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider returns tracer provider exporting spans with exporter,
+// e.g. tracetest.NewInMemoryExporter() can be used in tests.
+func NewProvider(serviceName string, exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+}
+
+// EndSpan records err if any and ends span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// HeadersCarrier adapts kafka message headers to propagation.TextMapCarrier
+type HeadersCarrier struct {
+	Headers *[]kafka.Header
+}
+
+var _ propagation.TextMapCarrier = HeadersCarrier{}
+
+// Get returns value of the last header with the given key
+func (c HeadersCarrier) Get(key string) string {
+	headers := *c.Headers
+	for i := len(headers) - 1; i >= 0; i-- {
+		if headers[i].Key == key {
+			return string(headers[i].Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces header with the given key or adds a new one
+func (c HeadersCarrier) Set(key string, value string) {
+	headers := *c.Headers
+	for i := range headers {
+		if headers[i].Key == key {
+			headers[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.Headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+// Keys returns keys of all headers
+func (c HeadersCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.Headers))
+	for _, h := range *c.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
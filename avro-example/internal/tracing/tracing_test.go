@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHeadersCarrier(t *testing.T) {
+	_, err := New(context.Background(), config.TracingConfig{Exporter: exporterNone}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider("test", exporter)
+	defer provider.Shutdown(context.Background())
+
+	member, err := baggage.NewMember("tenant", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bag, err := baggage.New(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, span := provider.Tracer("test").Start(baggage.ContextWithBaggage(context.Background(), bag), "publish")
+	defer span.End()
+
+	// stale trace context of forwarded message is replaced, other headers are kept
+	headers := []kafka.Header{
+		{Key: "traceparent", Value: []byte("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")},
+		{Key: "Course", Value: []byte("Kafka")},
+	}
+	carrier := HeadersCarrier{Headers: &headers}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	keys := carrier.Keys()
+	slices.Sort(keys)
+	if want := []string{"Course", "baggage", "traceparent"}; !slices.Equal(keys, want) {
+		t.Fatalf("got headers %v, want %v", keys, want)
+	}
+	if got := carrier.Get("Course"); got != "Kafka" {
+		t.Errorf("got Course header %q, want %q", got, "Kafka")
+	}
+
+	extracted := otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+	got := trace.SpanContextFromContext(extracted)
+	if !got.IsRemote() || got.TraceID() != span.SpanContext().TraceID() || got.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("got span context %v, want %v", got, span.SpanContext())
+	}
+	if got := baggage.FromContext(extracted).Member("tenant").Value(); got != "acme" {
+		t.Errorf("got baggage tenant %q, want %q", got, "acme")
+	}
+}
+
+func TestHeadersCarrierGetLast(t *testing.T) {
+	headers := []kafka.Header{{Key: "k", Value: []byte("first")}, {Key: "k", Value: []byte("last")}}
+	carrier := HeadersCarrier{Headers: &headers}
+	if got := carrier.Get("k"); got != "last" {
+		t.Errorf("got %q, want the last header value", got)
+	}
+	if got := carrier.Get("missing"); got != "" {
+		t.Errorf("got %q for missing header", got)
+	}
+}
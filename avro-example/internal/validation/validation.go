@@ -0,0 +1,278 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/avroschema"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+)
+
+var ErrInvalidRule = errors.New("invalid validation rule")
+
+// rule names reported in FieldError
+const (
+	RuleNotEmpty = "notEmpty"
+	RulePattern  = "pattern"
+	RuleMin      = "min"
+	RuleMax      = "max"
+	RuleEnum     = "enum"
+)
+
+// kinds of field values rules are applied to
+const (
+	kindString = "string"
+	kindBytes  = "bytes"
+	kindNumber = "number"
+	kindOther  = "other"
+)
+
+// FieldError describes a record field which value breaks a rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Error lists all invalid fields of record
+type Error struct {
+	Fields []FieldError
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, f.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validator checks records of type T, PT is inferred as *T
+type Validator[T any, PT dto.AvroRecord[T]] struct {
+	fields []fieldRule
+}
+
+// fieldRule is a rule of a single record field
+type fieldRule struct {
+	name    string
+	kind    string
+	rule    config.FieldRule
+	pattern *regexp.Regexp
+}
+
+// New returns validator applying rules from "validation" attributes of schema fields
+// and rules by field name, the latter override the former.
+// It fails if a rule refers to unknown field or doesn't fit field type.
+// Attributes are a part of the schema registered in schema registry,
+// so changing them registers a new schema version, config rules don't.
+func New[T any, PT dto.AvroRecord[T]](rules map[string]config.FieldRule) (*Validator[T, PT], error) {
+	var zero T
+	fields, err := avroschema.Parse(PT(&zero).Schema())
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Validator[T, PT]{}
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f.Name] = true
+		rule, ok := rules[f.Name]
+		if f.Validation == nil && !ok {
+			continue
+		}
+		if f.Validation != nil {
+			var schemaRule config.FieldRule
+			err := json.Unmarshal(f.Validation, &schemaRule)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrInvalidRule, f.Name, err)
+			}
+			rule = merge(schemaRule, rule)
+		}
+		compiled, err := compile(f.Name, kindOf(f), f.Nullable(), rule)
+		if err != nil {
+			return nil, err
+		}
+		v.fields = append(v.fields, compiled)
+	}
+	for name := range rules {
+		if !known[name] {
+			return nil, fmt.Errorf("%w: record has no field %s", ErrInvalidRule, name)
+		}
+	}
+	return v, nil
+}
+
+// merge returns base rule with checks set in override replaced
+func merge(base, override config.FieldRule) config.FieldRule {
+	if override.NotEmpty {
+		base.NotEmpty = true
+	}
+	if override.Pattern != "" {
+		base.Pattern = override.Pattern
+	}
+	if override.Min != nil {
+		base.Min = override.Min
+	}
+	if override.Max != nil {
+		base.Max = override.Max
+	}
+	if len(override.Enum) > 0 {
+		base.Enum = override.Enum
+	}
+	return base
+}
+
+// kindOf returns kind of field values, null member of union is ignored
+func kindOf(f avroschema.Field) string {
+	if f.Union == nil {
+		return primitiveKind(f.Type)
+	}
+	kind := ""
+	for _, member := range f.Union {
+		memberKind := primitiveKind(member)
+		if memberKind == "null" {
+			continue
+		}
+		if kind != "" && kind != memberKind {
+			return kindOther
+		}
+		kind = memberKind
+	}
+	return kind
+}
+
+// primitiveKind returns kind of values of non-union type
+func primitiveKind(name string) string {
+	switch name {
+	case "null":
+		return "null"
+	case "string", "enum":
+		return kindString
+	case "bytes", "fixed":
+		return kindBytes
+	case "int", "long", "float", "double":
+		return kindNumber
+	}
+	return kindOther
+}
+
+// compile checks that rule fits field kind and compiles its pattern
+func compile(name, kind string, nullable bool, rule config.FieldRule) (fieldRule, error) {
+	f := fieldRule{name: name, kind: kind, rule: rule}
+	if rule.NotEmpty && kind != kindString && kind != kindBytes && !nullable {
+		return f, fmt.Errorf("%w: %s: notEmpty requires string, bytes or nullable field", ErrInvalidRule, name)
+	}
+	if (rule.Pattern != "" || len(rule.Enum) > 0) && kind != kindString {
+		return f, fmt.Errorf("%w: %s: pattern and enum require string field", ErrInvalidRule, name)
+	}
+	if (rule.Min != nil || rule.Max != nil) && kind != kindNumber {
+		return f, fmt.Errorf("%w: %s: min and max require numeric field", ErrInvalidRule, name)
+	}
+	if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+		return f, fmt.Errorf("%w: %s: min is greater than max", ErrInvalidRule, name)
+	}
+	if rule.Pattern != "" {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return f, fmt.Errorf("%w: %s: %w", ErrInvalidRule, name, err)
+		}
+		f.pattern = pattern
+	}
+	return f, nil
+}
+
+// Validate returns *Error listing all invalid fields of msg or nil if msg is valid
+func (v *Validator[T, PT]) Validate(msg T) error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	// gogen-avro records are marshaled with avro field names
+	data, err := json.Marshal(PT(&msg))
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var values map[string]any
+	err = decoder.Decode(&values)
+	if err != nil {
+		return err
+	}
+
+	var errs []FieldError
+	for _, f := range v.fields {
+		errs = append(errs, f.check(unwrap(values[f.name]))...)
+	}
+	if len(errs) > 0 {
+		return &Error{Fields: errs}
+	}
+	return nil
+}
+
+// unwrap returns value of union member, unions are marshaled as {"<type>": value}
+func unwrap(value any) any {
+	wrapped, ok := value.(map[string]any)
+	if !ok || len(wrapped) != 1 {
+		return value
+	}
+	for _, member := range wrapped {
+		return member
+	}
+	return value
+}
+
+// check returns errors of value breaking the rule
+func (f fieldRule) check(value any) []FieldError {
+	if value == nil {
+		if f.rule.NotEmpty {
+			return []FieldError{f.error(RuleNotEmpty, "must not be null")}
+		}
+		return nil
+	}
+	var errs []FieldError
+	switch f.kind {
+	case kindString:
+		s, _ := value.(string)
+		if f.rule.NotEmpty && strings.TrimSpace(s) == "" {
+			errs = append(errs, f.error(RuleNotEmpty, "must not be empty"))
+		}
+		if f.pattern != nil && !f.pattern.MatchString(s) {
+			errs = append(errs, f.error(RulePattern, fmt.Sprintf("%q doesn't match %s", s, f.rule.Pattern)))
+		}
+		if len(f.rule.Enum) > 0 && !slices.Contains(f.rule.Enum, s) {
+			errs = append(errs, f.error(RuleEnum, fmt.Sprintf("%q is not one of %v", s, f.rule.Enum)))
+		}
+	case kindBytes:
+		s, _ := value.(string)
+		if f.rule.NotEmpty && s == "" {
+			errs = append(errs, f.error(RuleNotEmpty, "must not be empty"))
+		}
+	case kindNumber:
+		n, _ := value.(json.Number)
+		x, err := n.Float64()
+		if err != nil {
+			return []FieldError{f.error("type", fmt.Sprintf("%v is not a number", value))}
+		}
+		if f.rule.Min != nil && x < *f.rule.Min {
+			errs = append(errs, f.error(RuleMin, fmt.Sprintf("%v is less than %v", n, *f.rule.Min)))
+		}
+		if f.rule.Max != nil && x > *f.rule.Max {
+			errs = append(errs, f.error(RuleMax, fmt.Sprintf("%v is greater than %v", n, *f.rule.Max)))
+		}
+	}
+	return errs
+}
+
+func (f fieldRule) error(rule, message string) FieldError {
+	return FieldError{Field: f.name, Rule: rule, Message: message}
+}
@@ -0,0 +1,168 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/avroschema"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/config"
+	"github.com/AlexBlackNn/kafka-avro/avro-example/internal/dto"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestValidate(t *testing.T) {
+	valid := dto.User{Name: "Alice", Favorite_number: 7, Favorite_color: "red"}
+	tests := []struct {
+		name  string
+		rules map[string]config.FieldRule
+		user  dto.User
+		want  []FieldError
+	}{
+		{
+			name: "valid",
+			rules: map[string]config.FieldRule{
+				"name":            {Pattern: "^[A-Z][a-z]+$"},
+				"favorite_number": {Min: float(0), Max: float(10)},
+				"favorite_color":  {Enum: []string{"red", "blue"}},
+			},
+			user: valid,
+		},
+		{
+			name: "schema rule",
+			user: dto.User{Name: "  ", Favorite_number: 7},
+			want: []FieldError{{Field: "name", Rule: RuleNotEmpty, Message: "must not be empty"}},
+		},
+		{
+			// notEmpty of schema is kept when config adds pattern to the same field
+			name:  "config rule is merged with schema rule",
+			rules: map[string]config.FieldRule{"name": {Pattern: "^[a-z]+$"}},
+			user:  dto.User{Name: ""},
+			want: []FieldError{
+				{Field: "name", Rule: RuleNotEmpty, Message: "must not be empty"},
+				{Field: "name", Rule: RulePattern, Message: `"" doesn't match ^[a-z]+$`},
+			},
+		},
+		{
+			name: "all rules broken",
+			rules: map[string]config.FieldRule{
+				"favorite_number": {Min: float(0), Max: float(5)},
+				"favorite_color":  {NotEmpty: true, Enum: []string{"red", "blue"}},
+			},
+			user: dto.User{Name: "Bob", Favorite_number: -1},
+			want: []FieldError{
+				{Field: "favorite_number", Rule: RuleMin, Message: "-1 is less than 0"},
+				{Field: "favorite_color", Rule: RuleNotEmpty, Message: "must not be empty"},
+				{Field: "favorite_color", Rule: RuleEnum, Message: `"" is not one of [red blue]`},
+			},
+		},
+		{
+			name:  "max",
+			rules: map[string]config.FieldRule{"favorite_number": {Max: float(5)}},
+			user:  valid,
+			want:  []FieldError{{Field: "favorite_number", Rule: RuleMax, Message: "7 is greater than 5"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := New[dto.User](tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = v.Validate(tt.user)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("got %v, want valid", err)
+				}
+				return
+			}
+			var invalid *Error
+			if !errors.As(err, &invalid) {
+				t.Fatalf("got %v, want *Error", err)
+			}
+			if !reflect.DeepEqual(invalid.Fields, tt.want) {
+				t.Errorf("got %+v, want %+v", invalid.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInvalidRule(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules map[string]config.FieldRule
+	}{
+		{"unknown field", map[string]config.FieldRule{"age": {Min: float(0)}}},
+		{"pattern of number", map[string]config.FieldRule{"favorite_number": {Pattern: "^1"}}},
+		{"enum of number", map[string]config.FieldRule{"favorite_number": {Enum: []string{"1"}}}},
+		{"min of string", map[string]config.FieldRule{"name": {Min: float(1)}}},
+		{"notEmpty of number", map[string]config.FieldRule{"favorite_number": {NotEmpty: true}}},
+		{"min greater than max", map[string]config.FieldRule{"favorite_number": {Min: float(2), Max: float(1)}}},
+		{"bad pattern", map[string]config.FieldRule{"name": {Pattern: "("}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New[dto.User](tt.rules)
+			if !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("got %v, want %v", err, ErrInvalidRule)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	schema := config.FieldRule{NotEmpty: true, Pattern: "^a", Min: float(0), Enum: []string{"a"}}
+	override := config.FieldRule{Pattern: "^b", Max: float(9)}
+	want := config.FieldRule{NotEmpty: true, Pattern: "^b", Min: float(0), Max: float(9), Enum: []string{"a"}}
+	if got := merge(schema, override); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	// config can't switch notEmpty of schema off
+	if got := merge(schema, config.FieldRule{}); !reflect.DeepEqual(got, schema) {
+		t.Errorf("got %+v, want %+v", got, schema)
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		field avroschema.Field
+		want  string
+	}{
+		{avroschema.Field{Type: "string"}, kindString},
+		{avroschema.Field{Type: "enum"}, kindString},
+		{avroschema.Field{Type: "fixed"}, kindBytes},
+		{avroschema.Field{Type: "double"}, kindNumber},
+		{avroschema.Field{Type: "record"}, kindOther},
+		{avroschema.Field{Type: avroschema.TypeUnion, Union: []string{"null", "long"}}, kindNumber},
+		{avroschema.Field{Type: avroschema.TypeUnion, Union: []string{"null", "int", "string"}}, kindOther},
+	}
+	for _, tt := range tests {
+		if got := kindOf(tt.field); got != tt.want {
+			t.Errorf("got %s for %+v, want %s", got, tt.field, tt.want)
+		}
+	}
+	// nullable field of any kind may be required to be not null
+	f, err := compile("n", kindNumber, true, config.FieldRule{NotEmpty: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.check(nil); len(got) != 1 || got[0].Message != "must not be null" {
+		t.Errorf("got %+v for null", got)
+	}
+}
+
+func TestErrorFormat(t *testing.T) {
+	err := &Error{Fields: []FieldError{
+		{Field: "name", Rule: RuleNotEmpty, Message: "must not be empty"},
+		{Field: "favorite_number", Rule: RuleMin, Message: "-1 is less than 0"},
+	}}
+	if got, want := err.Fields[0].Error(), "name: must not be empty"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := err.Error(), "name: must not be empty; favorite_number: -1 is less than 0"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}